@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ivanovpetr/invasion/services/simulator"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagRuns    = "runs"
+	flagWorkers = "workers"
+
+	outputTable = "table"
+)
+
+func NewBatch() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "batch [path/to/map]",
+		Short: "runs many simulations of an invasion and aggregates their results",
+		Long: `A single invasion tells almost nothing about the expected damage. Batch runs many seeded simulations
+in parallel and estimates destruction probability of every city, distribution of surviving cities,
+mean number of turns before the invasion is over and distribution of the invasion end reasons.`,
+		Args: cobra.ExactArgs(1),
+		RunE: batchHandler,
+	}
+
+	addSimulationFlags(c)
+	c.Flags().Int(flagRuns, 1000, "Number of simulations")
+	c.Flags().Int(flagWorkers, runtime.NumCPU(), "Number of simulations running in parallel")
+	c.Flags().String(flagOutput, outputTable, "Output format: table or json")
+
+	return c
+}
+
+func batchHandler(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+
+	simulationCfg, cfgReport, err := simulationConfigFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	runs, _ := cmd.Flags().GetInt(flagRuns)
+	workers, _ := cmd.Flags().GetInt(flagWorkers)
+	cfg := simulator.BatchConfig{
+		Simulation: simulationCfg,
+		Runs:       runs,
+		Workers:    workers,
+		Seed:       seedFromFlags(cmd),
+	}
+	if err = cfg.Validate(); err != nil {
+		return err
+	}
+	output, _ := cmd.Flags().GetString(flagOutput)
+	if output != outputTable && output != outputJSON {
+		return fmt.Errorf("unknown output format %s, expected one of table,json", output)
+	}
+	// parse the provided map file
+	simulation, err := loadSimulation(cmd, filePath)
+	if err != nil {
+		return err
+	}
+	result, err := simulation.RunBatch(cfg)
+	if err != nil {
+		return err
+	}
+
+	if output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(batchReport{Config: cfgReport, Seed: cfg.Seed, BatchResult: result})
+	}
+	return writeBatchTable(os.Stdout, cfg.Seed, result)
+}
+
+// batchReport is a JSON document which describes a batch of simulations
+type batchReport struct {
+	Config configReport `json:"config"`
+	Seed   int64        `json:"seed"`
+	*simulator.BatchResult
+}
+
+// writeBatchTable writes the batch result as human-readable tables
+func writeBatchTable(out io.Writer, seed int64, result *simulator.BatchResult) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Seed:\t%d\n", seed)
+	fmt.Fprintf(w, "Runs:\t%d\n", result.Runs)
+	fmt.Fprintf(w, "Mean turns to end:\t%.2f\n", result.MeanTurns)
+	fmt.Fprintf(w, "Mean destroyed population:\t%.0f\n", result.MeanDestroyedPopulation)
+
+	fmt.Fprintln(w, "\nEND REASON\tRUNS\tSHARE")
+	reasons := make([]string, 0, len(result.EndReasons))
+	for reason := range result.EndReasons {
+		reasons = append(reasons, string(reason))
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		count := result.EndReasons[simulator.EndReason(reason)]
+		fmt.Fprintf(w, "%s\t%d\t%.2f%%\n", reason, count, share(count, result.Runs))
+	}
+
+	fmt.Fprintln(w, "\nSURVIVING CITIES\tRUNS\tSHARE")
+	surviving := make([]int, 0, len(result.SurvivingCities))
+	for n := range result.SurvivingCities {
+		surviving = append(surviving, n)
+	}
+	sort.Ints(surviving)
+	for _, n := range surviving {
+		count := result.SurvivingCities[n]
+		fmt.Fprintf(w, "%d\t%d\t%.2f%%\n", n, count, share(count, result.Runs))
+	}
+
+	fmt.Fprintln(w, "\nCITY\tDESTRUCTION PROBABILITY")
+	for _, d := range result.CityDestruction {
+		fmt.Fprintf(w, "%s\t%.4f\n", d.City, d.Probability)
+	}
+	return w.Flush()
+}
+
+// share returns the percentage of count in total
+func share(count, total int) float64 {
+	return float64(count) * 100 / float64(total)
+}
@@ -14,6 +14,13 @@ Using invasion you can simulate any type of aliens invasion scenario against any
 	}
 
 	c.AddCommand(NewSimulate())
+	c.AddCommand(NewBatch())
+	c.AddCommand(NewValidate())
+	c.AddCommand(NewFmt())
+	c.AddCommand(NewConvert())
+	c.AddCommand(NewExport())
+	c.AddCommand(NewWatch())
+	c.AddCommand(NewGenerate())
 
 	return c
 }
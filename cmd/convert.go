@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ivanovpetr/invasion/services/simulator"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagFrom = "from"
+	flagTo   = "to"
+
+	// standardStream is the path of the standard input or output
+	standardStream = "-"
+)
+
+func NewConvert() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "convert [path/to/input] [path/to/output]",
+		Short: "converts maps between emap, json and yaml formats",
+		Long: `Convert reads a map, checks it the same way as simulate does and writes it in another format.
+Formats are detected by file extensions, .emap, .json, .yaml or .yml, or by the content of the input.
+Use - to read the standard input or write the standard output, the output is written in the emap format unless --to is set.`,
+		Args: cobra.ExactArgs(2),
+		RunE: convertHandler,
+	}
+
+	c.Flags().String(flagFrom, "", "Format of the input: emap, json or yaml, detected if not provided")
+	c.Flags().String(flagTo, "", "Format of the output: emap, json or yaml, detected if not provided")
+
+	return c
+}
+
+func convertHandler(cmd *cobra.Command, args []string) error {
+	in, out := args[0], args[1]
+	from, _ := cmd.Flags().GetString(flagFrom)
+	to, _ := cmd.Flags().GetString(flagTo)
+
+	var src []byte
+	var err error
+	if in == standardStream {
+		src, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		src, err = ioutil.ReadFile(in)
+	}
+	if err != nil {
+		return err
+	}
+
+	decoder, err := mapCodec(from, in, src)
+	if err != nil {
+		return err
+	}
+	encoder, err := mapCodec(to, out, nil)
+	if err != nil {
+		return err
+	}
+
+	doc, err := decoder.Decode(bytes.NewReader(src), filepath.Base(in))
+	if err != nil {
+		return err
+	}
+	// the output is written only when the whole map is encoded, so a failed conversion doesn't damage the file
+	buf := bytes.Buffer{}
+	if err := encoder.Encode(&buf, doc); err != nil {
+		return err
+	}
+	return writeOutput(out, buf.Bytes())
+}
+
+// mapCodec returns the codec of the format with the name or detects the format of the file if the name is empty
+func mapCodec(name, path string, content []byte) (simulator.MapCodec, error) {
+	if name != "" {
+		return simulator.MapCodecByName(name)
+	}
+	return simulator.DetectMapCodec(path, content), nil
+}
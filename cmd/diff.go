@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around changes in a unified diff
+const diffContext = 3
+
+// diffOp is a single line of a line by line comparison
+type diffOp struct {
+	kind byte // ' ' for equal lines, '-' for removed lines and '+' for added lines
+	line string
+	// line numbers in the old and the new text, both start from 0
+	oldLine, newLine int
+}
+
+// unifiedDiff returns the difference between old and new texts in the unified diff format
+// or an empty string if the texts are equal
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(ops); {
+		// find the next change
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// a hunk lasts until there are more than two contexts of equal lines in a row
+		from := start - diffContext
+		if from < 0 {
+			from = 0
+		}
+		end, equal := start, 0
+		for ; end < len(ops) && equal <= 2*diffContext; end++ {
+			if ops[end].kind == ' ' {
+				equal++
+			} else {
+				equal = 0
+			}
+		}
+		end -= equal
+		end += diffContext
+		if end > len(ops) {
+			end = len(ops)
+		}
+		writeHunk(&builder, ops[from:end])
+		start = end
+	}
+	return builder.String()
+}
+
+// writeHunk writes a hunk of diff operations with its header
+func writeHunk(builder *strings.Builder, ops []diffOp) {
+	oldCount, newCount := 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+	fmt.Fprintf(builder, "@@ -%d,%d +%d,%d @@\n", hunkStart(ops[0].oldLine, oldCount), oldCount, hunkStart(ops[0].newLine, newCount), newCount)
+	for _, op := range ops {
+		builder.WriteByte(op.kind)
+		builder.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			builder.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkStart returns the number of the first line of a hunk side starting from 1. An empty side
+// starts at the line before the hunk, so an insertion at the top of a file starts at 0
+func hunkStart(line, count int) int {
+	if count == 0 {
+		return line
+	}
+	return line + 1
+}
+
+// splitLines splits text into lines which keep their line breaks, so the last line without a line break
+// differs from the same line with it
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) != 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines compares lines using the longest common subsequence. Common prefix and suffix
+// are cut off before the comparison, so formatting changes of big files stay cheap
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of midA[i:] and midB[j:]
+	lcs := make([][]int, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			switch {
+			case midA[i] == midB[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{kind: ' ', line: a[i], oldLine: i, newLine: i})
+	}
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		switch {
+		case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+			ops = append(ops, diffOp{kind: ' ', line: midA[i], oldLine: prefix + i, newLine: prefix + j})
+			i++
+			j++
+		case j == len(midB) || i < len(midA) && lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{kind: '-', line: midA[i], oldLine: prefix + i, newLine: prefix + j})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: midB[j], oldLine: prefix + i, newLine: prefix + j})
+			j++
+		}
+	}
+	for k := 0; k < suffix; k++ {
+		ops = append(ops, diffOp{kind: ' ', line: a[len(a)-suffix+k], oldLine: len(a) - suffix + k, newLine: len(b) - suffix + k})
+	}
+	return ops
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "A north=B\nB  south=A\nC\nD\nE\n"
+	formatted := "A north=B\nB south=A\nC\nD\nE\n"
+	require.Equal(t, `--- a
++++ b
+@@ -1,5 +1,5 @@
+ A north=B
+-B  south=A
++B south=A
+ C
+ D
+ E
+`, unifiedDiff("a", "b", old, formatted))
+	require.Empty(t, unifiedDiff("a", "b", old, old))
+}
+
+func TestUnifiedDiffMissingFinalNewline(t *testing.T) {
+	require.Equal(t, `--- a
++++ b
+@@ -1,2 +1,2 @@
+ A north=B
+-B south=A
+\ No newline at end of file
++B south=A
+`, unifiedDiff("a", "b", "A north=B\nB south=A", "A north=B\nB south=A\n"))
+}
+
+func TestUnifiedDiffInsertionIntoEmptyFile(t *testing.T) {
+	require.Equal(t, `--- a
++++ b
+@@ -0,0 +1,2 @@
++A north=B
++B south=A
+`, unifiedDiff("a", "b", "", "A north=B\nB south=A\n"))
+}
+
+func TestUnifiedDiffInsertionAtTheTop(t *testing.T) {
+	old := "A\nB\nC\nD\nE\nF\nG\nH\n"
+	require.Equal(t, `--- a
++++ b
+@@ -1,3 +1,4 @@
++# cities
+ A
+ B
+ C
+`, unifiedDiff("a", "b", old, "# cities\n"+old))
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagFormat = "format"
+	flagOut    = "out"
+
+	exportDOT = "dot"
+)
+
+func NewExport() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "export [path/to/map]",
+		Short: "renders a map for other tools",
+		Long: `Export renders a map file in a format of another tool. The dot format is a Graphviz graph
+where every road is an edge labelled with its direction, render it with dot -Tsvg.`,
+		Args: cobra.ExactArgs(1),
+		RunE: exportHandler,
+	}
+
+	c.Flags().String(flagFormat, exportDOT, "Export format: dot")
+	c.Flags().StringP(flagOut, "o", standardStream, "Path to the output file, - for the standard output")
+	c.Flags().Bool(flagInferReverse, false, "Add missing opposite directions to one-way roads of the map")
+
+	return c
+}
+
+func exportHandler(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString(flagFormat)
+	out, _ := cmd.Flags().GetString(flagOut)
+	if format != exportDOT {
+		return fmt.Errorf("unknown export format %s, expected one of dot", format)
+	}
+	simulation, err := loadSimulation(cmd, args[0])
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.Buffer{}
+	if err := simulation.WriteDOT(&buf); err != nil {
+		return err
+	}
+	return writeOutput(out, buf.Bytes())
+}
+
+// writeOutput writes data to the file or to the standard output if the path is -
+func writeOutput(path string, data []byte) error {
+	if path == standardStream {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
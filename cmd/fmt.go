@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ivanovpetr/invasion/services/simulator"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagWrite = "write"
+	flagDiff  = "diff"
+	flagCheck = "check"
+
+	flagFixSymmetry = "fix-symmetry"
+)
+
+func NewFmt() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "fmt [path/to/map]...",
+		Short: "rewrites map files in the canonical format",
+		Long: `Fmt parses map files and prints them in the canonical format: cities keep their order,
+directions go in the order of the map topology, north, east, south, west by default, all tokens are separated by single spaces and comments are kept.
+With --fix-symmetry fmt also adds the missing opposite directions to one-way roads and fails when the opposite
+direction is already taken by another city. Without files fmt formats the standard input.`,
+		RunE: fmtHandler,
+	}
+
+	c.Flags().BoolP(flagWrite, "w", false, "Write the result to the source file instead of the standard output")
+	c.Flags().BoolP(flagDiff, "d", false, "Print diffs instead of formatted maps")
+	c.Flags().Bool(flagCheck, false, "Print names of files which are not formatted and fail if there are any")
+	c.Flags().Bool(flagFixSymmetry, false, "Add missing opposite directions to one-way roads")
+
+	return c
+}
+
+// fmtOptions tells fmt what to do with formatted maps
+type fmtOptions struct {
+	write, diff, check, fixSymmetry bool
+}
+
+func fmtHandler(cmd *cobra.Command, args []string) error {
+	opts := fmtOptions{}
+	opts.write, _ = cmd.Flags().GetBool(flagWrite)
+	opts.diff, _ = cmd.Flags().GetBool(flagDiff)
+	opts.check, _ = cmd.Flags().GetBool(flagCheck)
+	opts.fixSymmetry, _ = cmd.Flags().GetBool(flagFixSymmetry)
+
+	if len(args) == 0 {
+		if opts.write {
+			return fmt.Errorf("cannot use -w flag with the standard input")
+		}
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		changed, err := formatSource(opts, "<standard input>", src)
+		if err != nil {
+			return err
+		}
+		if opts.check && changed {
+			return fmt.Errorf("the standard input is not formatted")
+		}
+		return nil
+	}
+
+	unformatted := 0
+	for _, path := range args {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		changed, err := formatSource(opts, path, src)
+		if err != nil {
+			return err
+		}
+		if changed {
+			unformatted++
+		}
+	}
+	if opts.check && unformatted != 0 {
+		return fmt.Errorf("%d files are not formatted", unformatted)
+	}
+	return nil
+}
+
+// formatSource formats the map source and handles the result according to the options.
+// Returns true if the formatted map differs from the source
+func formatSource(opts fmtOptions, path string, src []byte) (bool, error) {
+	format := simulator.FormatMap
+	if opts.fixSymmetry {
+		format = simulator.FixMapSymmetry
+	}
+	formatted, err := format(bytes.NewReader(src), filepath.Base(path))
+	if err != nil {
+		return false, err
+	}
+	changed := !bytes.Equal(src, formatted)
+
+	if opts.check {
+		if changed {
+			fmt.Println(path)
+		}
+		return changed, nil
+	}
+	if opts.diff {
+		fmt.Print(unifiedDiff(path+".orig", path, string(src), string(formatted)))
+	}
+	if opts.write {
+		if changed {
+			return true, ioutil.WriteFile(path, formatted, 0644)
+		}
+		return false, nil
+	}
+	if !opts.diff {
+		_, err = os.Stdout.Write(formatted)
+	}
+	return changed, err
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/ivanovpetr/invasion/services/simulator"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagCities      = "cities"
+	flagShape       = "topology"
+	flagDensity     = "density"
+	flagSymmetric   = "symmetric"
+	flagDirections  = "directions"
+	flagIslands     = "islands"
+	flagChains      = "chains"
+	flagChainLength = "chain-length"
+	flagDeadEnds    = "dead-ends"
+)
+
+func NewGenerate() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "generate",
+		Short: "generates a random map",
+		Long: `Generate writes a random valid map. Cities are connected in one of the shapes: grid, random, ring or scale-free.
+The same flags and seed always give the same map, so generated maps are handy for benchmarks and reproducing failures.
+Islands split the map into parts not connected with each other, chains and dead ends add cities to it.`,
+		Args: cobra.NoArgs,
+		RunE: generateHandler,
+	}
+
+	c.Flags().Int(flagCities, 20, "Number of cities besides cities of chains and dead ends")
+	c.Flags().String(flagShape, simulator.ShapeGrid, "Shape of the map: grid, random, ring or scale-free")
+	c.Flags().Float64(flagDensity, 0.7, "Probability to build every optional road, from 0 to 1")
+	c.Flags().Int64(flagSeed, 0, "Seed for the random generator, a time based seed is used if not provided")
+	c.Flags().Bool(flagSymmetric, false, "Make every road two-way, otherwise half of roads are one-way")
+	c.Flags().String(flagDirections, simulator.TopologyCompass, "Topology of the map: compass, octagonal, hex or a list of directions")
+	c.Flags().Int(flagIslands, 1, "Number of parts of the map which are not connected with each other")
+	c.Flags().Int(flagChains, 0, "Number of long chains of cities attached to the map")
+	c.Flags().Int(flagChainLength, 10, "Number of cities of every chain")
+	c.Flags().Int(flagDeadEnds, 0, "Number of cities with a single road attached to the map")
+	c.Flags().String(flagFormat, simulator.FormatEmap, "Format of the map: emap, json or yaml")
+	c.Flags().StringP(flagOut, "o", standardStream, "Path to the output file, - for the standard output")
+
+	return c
+}
+
+func generateHandler(cmd *cobra.Command, _ []string) error {
+	cities, _ := cmd.Flags().GetInt(flagCities)
+	shape, _ := cmd.Flags().GetString(flagShape)
+	density, _ := cmd.Flags().GetFloat64(flagDensity)
+	symmetric, _ := cmd.Flags().GetBool(flagSymmetric)
+	directions, _ := cmd.Flags().GetString(flagDirections)
+	islands, _ := cmd.Flags().GetInt(flagIslands)
+	chains, _ := cmd.Flags().GetInt(flagChains)
+	chainLength, _ := cmd.Flags().GetInt(flagChainLength)
+	deadEnds, _ := cmd.Flags().GetInt(flagDeadEnds)
+	format, _ := cmd.Flags().GetString(flagFormat)
+	out, _ := cmd.Flags().GetString(flagOut)
+
+	topology, err := simulator.ParseTopology(directions)
+	if err != nil {
+		return err
+	}
+	codec, err := simulator.MapCodecByName(format)
+	if err != nil {
+		return err
+	}
+	seed := seedFromFlags(cmd)
+	doc, err := simulator.GenerateMap(simulator.GenerateOptions{
+		Cities:      cities,
+		Shape:       shape,
+		Density:     density,
+		Seed:        seed,
+		Symmetric:   symmetric,
+		Topology:    topology,
+		Islands:     islands,
+		Chains:      chains,
+		ChainLength: chainLength,
+		DeadEnds:    deadEnds,
+	})
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.Buffer{}
+	if err := codec.Encode(&buf, doc); err != nil {
+		return err
+	}
+	// the map may be written to the standard output, so the seed goes to the standard error
+	fmt.Fprintf(os.Stderr, "Seed: %d\n", seed)
+	return writeOutput(out, buf.Bytes())
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ivanovpetr/invasion/services/simulator"
+)
+
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+)
+
+// marshalEvent encodes the event as a JSON object with an additional type field
+func marshalEvent(e simulator.Event) (json.RawMessage, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event %s: %w", e.EventName(), err)
+	}
+	// every event is a non-empty JSON object, so the type field is put right after the opening brace
+	return append([]byte(fmt.Sprintf(`{"type":%q,`, e.EventName())), data[1:]...), nil
+}
+
+// ndjsonWriter is an event sink which writes every event as a separate JSON line
+type ndjsonWriter struct {
+	out io.Writer
+	err error
+}
+
+// writeSeed writes the seed of the simulation as the leading line, so the stream is enough to replay the simulation
+func (w *ndjsonWriter) writeSeed(seed int64) {
+	data, err := json.Marshal(struct {
+		Type string `json:"type"`
+		Seed int64  `json:"seed"`
+	}{Type: "Seed", Seed: seed})
+	if err != nil {
+		w.err = err
+		return
+	}
+	_, w.err = w.out.Write(append(data, '\n'))
+}
+
+func (w *ndjsonWriter) Emit(e simulator.Event) {
+	if w.err != nil {
+		return
+	}
+	data, err := marshalEvent(e)
+	if err != nil {
+		w.err = err
+		return
+	}
+	_, w.err = w.out.Write(append(data, '\n'))
+}
+
+// configReport describes the simulation configuration in a JSON report
+type configReport struct {
+	Aliens               int64  `json:"aliens"`
+	MaxTurns             int    `json:"maxTurns"`
+	DestructionThreshold int    `json:"destructionThreshold"`
+	Spawn                string `json:"spawn"`
+	Movement             string `json:"movement"`
+}
+
+// simulationReport is a JSON document which describes a whole simulation
+type simulationReport struct {
+	Config              configReport          `json:"config"`
+	Seed                int64                 `json:"seed"`
+	Events              []json.RawMessage     `json:"events"`
+	SurvivingCities     []string              `json:"survivingCities"`
+	DestroyedCities     []string              `json:"destroyedCities"`
+	DestroyedPopulation int64                 `json:"destroyedPopulation"`
+	Aliens              []simulator.AlienInfo `json:"aliens"`
+	ResultMap           string                `json:"resultMap"`
+}
+
+// writeJSONReport writes the simulation result as a single JSON document
+func writeJSONReport(out io.Writer, cfg configReport, seed int64, result *simulator.SimulationResult) error {
+	report := simulationReport{
+		Config:              cfg,
+		Seed:                seed,
+		Events:              make([]json.RawMessage, 0, len(result.Events)),
+		SurvivingCities:     result.SurvivingCities(),
+		DestroyedCities:     result.DestroyedCities(),
+		DestroyedPopulation: result.DestroyedPopulation(),
+		Aliens:              result.AlienStates(),
+	}
+	for _, e := range result.Events {
+		data, err := marshalEvent(e)
+		if err != nil {
+			return err
+		}
+		report.Events = append(report.Events, data)
+	}
+	resultMap := bytes.Buffer{}
+	if err := result.PrintResultMap(&resultMap); err != nil {
+		return err
+	}
+	report.ResultMap = resultMap.String()
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
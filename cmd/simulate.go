@@ -1,16 +1,30 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
-	"math/rand"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/ivanovpetr/invasion/services/simulator"
 	"github.com/spf13/cobra"
 )
 
-const flagAliensNumber = "n"
+const (
+	flagAliensNumber = "n"
+	flagSeed         = "seed"
+	flagSpawn        = "spawn"
+	flagMovement     = "movement"
+	flagMaxTurns     = "max-turns"
+	flagThreshold    = "threshold"
+	flagOutput       = "output"
+	flagInferReverse = "infer-reverse-roads"
+	flagDOT          = "dot"
+	flagReport       = "report"
+)
 
 func NewSimulate() *cobra.Command {
 	c := &cobra.Command{
@@ -22,25 +36,160 @@ Using invasion you can simulate any type of aliens invasion scenario against any
 		RunE: simulateHandler,
 	}
 
-	c.Flags().Int(flagAliensNumber, 15, "Number of aliens during the simulation")
+	addSimulationFlags(c)
+	c.Flags().String(flagOutput, outputText, "Output format: text, json or ndjson")
+	c.Flags().String(flagDOT, "", "Write the resulting map as a Graphviz DOT graph to the file")
+	c.Flags().String(flagReport, "", "Write a self-contained HTML report of the simulation to the file")
 
 	return c
 }
 
+// addSimulationFlags adds flags which configure a simulation to the command
+func addSimulationFlags(c *cobra.Command) {
+	defaults := simulator.DefaultSimulationConfig(0)
+	c.Flags().Int(flagAliensNumber, 15, "Number of aliens during the simulation")
+	c.Flags().Int(flagMaxTurns, defaults.MaxTurns, "Maximum number of turns of the simulation")
+	c.Flags().Int(flagThreshold, defaults.DestructionThreshold, "Number of aliens which destroy a city when they meet in it")
+	c.Flags().String(flagSpawn, "uniform", "Spawn strategy of aliens: uniform, weighted:City1=3,City2=1, cluster:City:hops, list:City1,City2 or population")
+	c.Flags().String(flagMovement, "random", "Movement strategy of aliens: random, flock, explore, cheap, stay:probability or bias:direction:weight")
+	c.Flags().Int64(flagSeed, 0, "Seed for the random generator, a time based seed is used if not provided")
+	c.Flags().Bool(flagInferReverse, false, "Add missing opposite directions to one-way roads of the map")
+}
+
+// loadSimulation parses the map file with options provided with the command flags
+func loadSimulation(cmd *cobra.Command, path string) (*simulator.Simulation, error) {
+	inferReverse, _ := cmd.Flags().GetBool(flagInferReverse)
+	return simulator.CreateSimulationFromPathWithOptions(path, simulator.ParseOptions{InferReverseRoads: inferReverse})
+}
+
+// simulationConfigFromFlags creates simulation config from the command flags
+func simulationConfigFromFlags(cmd *cobra.Command) (simulator.SimulationConfig, configReport, error) {
+	numberOfAliens, _ := cmd.Flags().GetInt(flagAliensNumber)
+	maxTurns, _ := cmd.Flags().GetInt(flagMaxTurns)
+	threshold, _ := cmd.Flags().GetInt(flagThreshold)
+	spawnSpec, _ := cmd.Flags().GetString(flagSpawn)
+	movementSpec, _ := cmd.Flags().GetString(flagMovement)
+	report := configReport{
+		Aliens:               int64(numberOfAliens),
+		MaxTurns:             maxTurns,
+		DestructionThreshold: threshold,
+		Spawn:                spawnSpec,
+		Movement:             movementSpec,
+	}
+
+	spawnStrategy, err := simulator.ParseSpawnStrategy(spawnSpec)
+	if err != nil {
+		return simulator.SimulationConfig{}, report, err
+	}
+	movementStrategy, err := simulator.ParseMovementStrategy(movementSpec)
+	if err != nil {
+		return simulator.SimulationConfig{}, report, err
+	}
+	cfg := simulator.SimulationConfig{
+		Aliens:               report.Aliens,
+		MaxTurns:             maxTurns,
+		DestructionThreshold: threshold,
+		Spawn:                spawnStrategy,
+		Movement:             movementStrategy,
+	}
+	return cfg, report, cfg.Validate()
+}
+
+// seedFromFlags returns the seed provided with the command flags or a time based seed
+func seedFromFlags(cmd *cobra.Command) int64 {
+	seed, _ := cmd.Flags().GetInt64(flagSeed)
+	if !cmd.Flags().Changed(flagSeed) {
+		seed = time.Now().UnixNano()
+	}
+	return seed
+}
+
 func simulateHandler(cmd *cobra.Command, args []string) error {
 	filePath := args[0]
 
-	rand.Seed(time.Now().UnixNano())
-	numberOfAliens, _ := cmd.Flags().GetInt(flagAliensNumber)
+	cfg, cfgReport, err := simulationConfigFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	seed := seedFromFlags(cmd)
+	output, _ := cmd.Flags().GetString(flagOutput)
+	if output != outputText && output != outputJSON && output != outputNDJSON {
+		return fmt.Errorf("unknown output format %s, expected one of text,json,ndjson", output)
+	}
 	// parse the provided map file
-	simulation, err := simulator.CreateSimulationFromPath(filePath)
+	simulation, err := loadSimulation(cmd, filePath)
+	if err != nil {
+		return err
+	}
+	simulation.SetSeed(seed)
+
+	result, err := runSimulation(simulation, cfg, cfgReport, seed, output)
 	if err != nil {
 		return err
 	}
-	result := simulation.Run(int64(numberOfAliens))
-	for _, log := range result.Logs {
-		fmt.Println(log)
+	if dotPath, _ := cmd.Flags().GetString(flagDOT); dotPath != "" {
+		buf := bytes.Buffer{}
+		if err := result.WriteDOT(&buf); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(dotPath, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+	if reportPath, _ := cmd.Flags().GetString(flagReport); reportPath != "" {
+		buf := bytes.Buffer{}
+		if err := result.WriteHTMLReport(&buf, reportOptions(filePath, seed, cfgReport)); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(reportPath, buf.Bytes(), 0644)
 	}
-	_ = result.PrintResultMap(os.Stdout)
 	return nil
 }
+
+// reportOptions describes the simulation in the HTML report
+func reportOptions(path string, seed int64, cfg configReport) simulator.ReportOptions {
+	return simulator.ReportOptions{
+		Title: "Invasion of " + filepath.Base(path),
+		Details: []simulator.ReportDetail{
+			{Name: "Map", Value: path},
+			{Name: "Seed", Value: strconv.FormatInt(seed, 10)},
+			{Name: "Spawn", Value: cfg.Spawn},
+			{Name: "Movement", Value: cfg.Movement},
+			{Name: "Destruction threshold", Value: strconv.Itoa(cfg.DestructionThreshold)},
+			{Name: "Max turns", Value: strconv.Itoa(cfg.MaxTurns)},
+		},
+	}
+}
+
+// runSimulation runs the simulation and writes its events and result in the output format
+func runSimulation(simulation *simulator.Simulation, cfg simulator.SimulationConfig, cfgReport configReport, seed int64, output string) (*simulator.SimulationResult, error) {
+	switch output {
+	case outputJSON:
+		result, err := simulation.Run(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return result, writeJSONReport(os.Stdout, cfgReport, seed, result)
+	case outputNDJSON:
+		writer := &ndjsonWriter{out: os.Stdout}
+		writer.writeSeed(seed)
+		cfg.Sink = writer
+		result, err := simulation.Run(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return result, writer.err
+	default:
+		fmt.Printf("Seed: %d\n", seed)
+		renderer := simulator.NewTextRenderer(os.Stdout)
+		cfg.Sink = renderer
+		result, err := simulation.Run(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err = renderer.Err(); err != nil {
+			return nil, err
+		}
+		return result, result.PrintResultMap(os.Stdout)
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ivanovpetr/invasion/services/simulator"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagStrict = "strict"
+	flagLayout = "layout"
+)
+
+func NewValidate() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "validate [path/to/map]...",
+		Short: "checks map files without running an invasion",
+		Long: `Validate parses map files and checks them with lint rules: roads without a way back, isolated and unreachable cities,
+parts of a map disconnected from each other, closed neighbourhoods aliens can't leave and roads whose directions
+contradict each other.
+Every finding has a severity. Validation fails when there are errors, or warnings in strict mode.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: validateHandler,
+	}
+
+	c.Flags().Bool(flagStrict, false, "Treat warnings as errors")
+	c.Flags().Bool(flagLayout, false, "Print grid coordinates of cities derived from directions of roads")
+
+	return c
+}
+
+func validateHandler(cmd *cobra.Command, args []string) error {
+	strict, _ := cmd.Flags().GetBool(flagStrict)
+	showLayout, _ := cmd.Flags().GetBool(flagLayout)
+
+	errorsCount, warningsCount := 0, 0
+	for _, path := range args {
+		findings, err := simulator.LintMapFromPath(path)
+		if err != nil {
+			return err
+		}
+		fileErrors := 0
+		for _, f := range findings {
+			fmt.Fprintln(os.Stdout, f.String())
+			switch f.Severity {
+			case simulator.SeverityError:
+				fileErrors++
+			case simulator.SeverityWarning:
+				warningsCount++
+			}
+		}
+		errorsCount += fileErrors
+		// a map with errors can't be laid out
+		if showLayout && fileErrors == 0 {
+			if err := printLayout(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if errorsCount != 0 || strict && warningsCount != 0 {
+		return fmt.Errorf("validation failed: %d errors, %d warnings", errorsCount, warningsCount)
+	}
+	fmt.Fprintf(os.Stdout, "validation passed: %d errors, %d warnings\n", errorsCount, warningsCount)
+	return nil
+}
+
+// printLayout prints grid coordinates of cities of the map, x grows to the east and y to the south
+func printLayout(path string) error {
+	simulation, err := simulator.CreateSimulationFromPath(path)
+	if err != nil {
+		return err
+	}
+	positions := simulation.Layout().Positions
+	names := make([]string, 0, len(positions))
+	for name := range positions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(os.Stdout, "layout of %s:\n", path)
+	for _, name := range names {
+		fmt.Fprintf(os.Stdout, "\t%s %d,%d\n", name, positions[name].X, positions[name].Y)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ivanovpetr/invasion/services/simulator"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+const (
+	flagFPS = "fps"
+
+	// flashFrames is the number of frames a city blinks after a battle
+	flashFrames = 6
+	// maxFPS limits the speed of the animation
+	maxFPS = 60
+)
+
+func NewWatch() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "watch [path/to/map]",
+		Short: "animates an invasion in the terminal",
+		Long: `Watch draws the map in the terminal as a grid derived from directions of roads and plays the invasion turn by turn.
+Every city shows aliens in it with * and aliens on the way to it with ~, cities blink after battles.
+Controls: space pauses and resumes, n plays a single turn, + and - change the speed, q quits.`,
+		Args: cobra.ExactArgs(1),
+		RunE: watchHandler,
+	}
+
+	addSimulationFlags(c)
+	c.Flags().Int(flagFPS, 5, "Number of turns played per second")
+
+	return c
+}
+
+// watchState is the state of the animation controlled by keys
+type watchState struct {
+	fps    int
+	paused bool
+	quit   bool
+}
+
+// handleKey changes the state of the animation and returns true when the key asks to play a single turn
+func (w *watchState) handleKey(key byte) (step bool) {
+	switch key {
+	case ' ':
+		w.paused = !w.paused
+	case 'n', 's':
+		w.paused = true
+		return true
+	case '+', '=':
+		if w.fps < maxFPS {
+			w.fps++
+		}
+	case '-', '_':
+		if w.fps > 1 {
+			w.fps--
+		}
+	case 'q', 3:
+		w.quit = true
+	}
+	return false
+}
+
+func watchHandler(cmd *cobra.Command, args []string) error {
+	cfg, _, err := simulationConfigFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	fps, _ := cmd.Flags().GetInt(flagFPS)
+	if fps <= 0 || fps > maxFPS {
+		return fmt.Errorf("fps must be between 1 and %d, got %d", maxFPS, fps)
+	}
+	simulation, err := loadSimulation(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	seed := seedFromFlags(cmd)
+	simulation.SetSeed(seed)
+
+	// events of the current turn are collected to show battles
+	var turnEvents []simulator.Event
+	cfg.Sink = simulator.EventSinkFunc(func(e simulator.Event) { turnEvents = append(turnEvents, e) })
+	cfg.DiscardEvents = true
+	run, err := simulation.NewRun(cfg)
+	if err != nil {
+		return err
+	}
+
+	keys := make(chan byte)
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	if interactive {
+		oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+		if err != nil {
+			return err
+		}
+		defer term.Restore(int(os.Stdin.Fd()), oldState)
+		go readKeys(os.Stdin, keys)
+	}
+	out := os.Stdout
+	// the cursor is hidden during the animation, escapes are not written into a redirected output
+	if term.IsTerminal(int(out.Fd())) {
+		fmt.Fprint(out, "\x1b[?25l")
+		defer fmt.Fprint(out, "\x1b[?25h")
+	}
+
+	grid := simulator.NewTerminalGrid(simulation)
+	state := watchState{fps: fps}
+	flash := map[string]int{}
+	var messages []string
+	step := func() {
+		turnEvents = turnEvents[:0]
+		run.Step()
+		for _, e := range turnEvents {
+			if b, ok := e.(simulator.BattleOccurred); ok {
+				flash[b.City] = flashFrames
+			}
+			if msg, ok := simulator.RenderEvent(e); ok {
+				messages = append(messages, msg)
+			}
+		}
+	}
+	draw := func() {
+		blinking := map[string]bool{}
+		for name, frames := range flash {
+			// cities blink every other frame until their flash is over
+			blinking[name] = frames%2 == 0
+		}
+		drawWatch(out, grid.Render(run.State(), blinking, true), run, state, seed, lastLines(messages, 5))
+	}
+	if !interactive {
+		keys = nil
+	}
+
+	draw()
+	tick := time.After(time.Second / time.Duration(state.fps))
+	for !state.quit {
+		// without controls there is nothing to wait for after the end
+		if run.Done() && keys == nil {
+			break
+		}
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				// the input is closed, the animation goes on without controls
+				keys = nil
+				continue
+			}
+			fps := state.fps
+			// keys never play turns except a single turn on request
+			if state.handleKey(key) && !run.Done() {
+				step()
+			}
+			if state.fps != fps {
+				tick = time.After(time.Second / time.Duration(state.fps))
+			}
+		case <-tick:
+			tick = time.After(time.Second / time.Duration(state.fps))
+			if !state.paused && !run.Done() {
+				step()
+			}
+			for name := range flash {
+				if flash[name]--; flash[name] == 0 {
+					delete(flash, name)
+				}
+			}
+		}
+		draw()
+	}
+	return nil
+}
+
+// drawWatch clears the terminal and draws the frame. Lines end with \r\n because the terminal is in raw mode
+func drawWatch(out io.Writer, grid []string, run *simulator.Run, state watchState, seed int64, messages []string) {
+	status := "playing"
+	switch {
+	case run.Done():
+		status = "over, press q to quit"
+	case state.paused:
+		status = "paused"
+	}
+	lines := []string{fmt.Sprintf("Turn %d, seed %d, %d fps, %s", run.Turn(), seed, state.fps, status), ""}
+	lines = append(lines, grid...)
+	lines = append(lines, "")
+	lines = append(lines, messages...)
+	lines = append(lines, "", "space pause/resume  n step  +/- speed  q quit")
+	fmt.Fprint(out, "\x1b[H\x1b[2J"+strings.Join(lines, "\x1b[K\r\n")+"\x1b[K\r\n")
+}
+
+// readKeys sends pressed keys to the channel until the input is closed
+func readKeys(in io.Reader, keys chan<- byte) {
+	buf := make([]byte, 1)
+	for {
+		if _, err := in.Read(buf); err != nil {
+			close(keys)
+			return
+		}
+		keys <- buf[0]
+	}
+}
+
+// lastLines returns at most n last lines
+func lastLines(lines []string, n int) []string {
+	if len(lines) > n {
+		return lines[len(lines)-n:]
+	}
+	return lines
+}
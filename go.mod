@@ -3,6 +3,9 @@ module github.com/ivanovpetr/invasion
 go 1.16
 
 require (
-	github.com/spf13/cobra v1.2.1 // indirect
-	github.com/stretchr/testify v1.7.0 // indirect
+	github.com/spf13/cobra v1.2.1
+	github.com/stretchr/testify v1.7.0
+	golang.org/x/term v0.0.0-20210503060354-a79de5458b56
+	golang.org/x/text v0.3.5
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
@@ -0,0 +1,239 @@
+package layout
+
+import "sort"
+
+// Point is a cell of the grid nodes are laid out on, Y grows to the south
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Add returns the point moved by the offset
+func (p Point) Add(offset Point) Point {
+	return Point{X: p.X + offset.X, Y: p.Y + offset.Y}
+}
+
+// Sub returns the offset of the point relative to q
+func (p Point) Sub(q Point) Point {
+	return Point{X: p.X - q.X, Y: p.Y - q.Y}
+}
+
+// Link is a road from one node to another
+type Link struct {
+	From string
+	To   string
+	// Direction is the name of the direction of the road, it helps to report conflicts
+	Direction string
+	// Offset is the position of To relative to From
+	Offset Point
+	// Geometric specifies either the direction of the road has a geometric meaning. Other roads only keep
+	// their nodes next to each other and never conflict
+	Geometric bool
+}
+
+// Conflict is a road whose direction contradicts the position other roads give to its node
+type Conflict struct {
+	Link Link
+	// Path is the chain of roads which placed Link.To relative to Link.From, from Link.From to Link.To.
+	// Roads are kept as they were declared, so some of them lead backwards
+	Path []Link
+}
+
+// Layout is the result of laying out nodes on the grid
+type Layout struct {
+	// Positions are cells of nodes, every node has its own cell
+	Positions map[string]Point
+	// Conflicts are roads which contradict other roads
+	Conflicts []Conflict
+	// Overlaps are groups of nodes which roads put on the same cell
+	Overlaps [][]string
+}
+
+// edge is a link seen from one of its nodes
+type edge struct {
+	to     string
+	offset Point
+	link   Link
+}
+
+// Compute places every node on the grid following offsets of links, so a node is placed at the offset
+// of the link from the node it is linked from. A node whose cell is taken gets the nearest free cell,
+// links without a geometric meaning place nodes next to each other. Parts of the graph which aren't connected
+// are placed from left to right.
+// Besides positions Compute finds links which contradict the geometry: every link has to agree with positions
+// other links give to its nodes, and no two nodes may be put on the same cell
+func Compute(nodes []string, links []Link) *Layout {
+	names := append([]string{}, nodes...)
+	sort.Strings(names)
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name] = true
+	}
+
+	edges := map[string][]edge{}
+	var valid []Link
+	for _, l := range links {
+		if !known[l.From] || !known[l.To] || l.From == l.To {
+			continue
+		}
+		valid = append(valid, l)
+		edges[l.From] = append(edges[l.From], edge{to: l.To, offset: l.Offset, link: l})
+		edges[l.To] = append(edges[l.To], edge{to: l.From, offset: Point{X: -l.Offset.X, Y: -l.Offset.Y}, link: l})
+	}
+
+	l := &Layout{Positions: place(names, edges)}
+	l.Conflicts, l.Overlaps = check(names, edges, valid)
+	return l
+}
+
+// place lays out connected parts of the graph one by one
+func place(names []string, edges map[string][]edge) map[string]Point {
+	positions := make(map[string]Point, len(names))
+	left := 0
+	for _, name := range names {
+		if _, ok := positions[name]; ok {
+			continue
+		}
+		component := map[string]Point{name: {}}
+		occupied := map[Point]bool{{}: true}
+		queue := []string{name}
+		for len(queue) != 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for _, e := range edges[current] {
+				if _, ok := component[e.to]; ok {
+					continue
+				}
+				wanted := component[current]
+				if e.link.Geometric {
+					wanted = wanted.Add(e.offset)
+				}
+				p := nearestFreeCell(occupied, wanted)
+				component[e.to] = p
+				occupied[p] = true
+				queue = append(queue, e.to)
+			}
+		}
+
+		minX, minY, maxX := 0, 0, 0
+		for _, p := range component {
+			if p.X < minX {
+				minX = p.X
+			}
+			if p.Y < minY {
+				minY = p.Y
+			}
+			if p.X > maxX {
+				maxX = p.X
+			}
+		}
+		for n, p := range component {
+			positions[n] = Point{X: p.X - minX + left, Y: p.Y - minY}
+		}
+		left += maxX - minX + 2
+	}
+	return positions
+}
+
+// check propagates offsets of geometric links without resolving taken cells and finds links which disagree
+// with the propagated positions and nodes which end up on the same cell
+func check(names []string, edges map[string][]edge, links []Link) ([]Conflict, [][]string) {
+	ideal := make(map[string]Point, len(names))
+	// the edge each node was reached by and the first node of its part of the graph
+	parents := map[string]edge{}
+	roots := map[string]string{}
+	for _, name := range names {
+		if _, ok := ideal[name]; ok {
+			continue
+		}
+		ideal[name] = Point{}
+		roots[name] = name
+		queue := []string{name}
+		for len(queue) != 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for _, e := range edges[current] {
+				if _, ok := ideal[e.to]; ok || !e.link.Geometric {
+					continue
+				}
+				ideal[e.to] = ideal[current].Add(e.offset)
+				parents[e.to] = edge{to: current, link: e.link}
+				roots[e.to] = name
+				queue = append(queue, e.to)
+			}
+		}
+	}
+
+	var conflicts []Conflict
+	for _, l := range links {
+		if l.Geometric && ideal[l.To].Sub(ideal[l.From]) != l.Offset {
+			conflicts = append(conflicts, Conflict{Link: l, Path: path(parents, l.From, l.To)})
+		}
+	}
+
+	cells := map[string]map[Point][]string{}
+	for _, name := range names {
+		root := roots[name]
+		if cells[root] == nil {
+			cells[root] = map[Point][]string{}
+		}
+		cells[root][ideal[name]] = append(cells[root][ideal[name]], name)
+	}
+	var overlaps [][]string
+	for _, name := range names {
+		// names are sorted, so every group is reported once by its first node
+		if group := cells[roots[name]][ideal[name]]; len(group) > 1 && group[0] == name {
+			overlaps = append(overlaps, group)
+		}
+	}
+	return conflicts, overlaps
+}
+
+// path returns links of the tree of parents which lead from one node to another
+func path(parents map[string]edge, from, to string) []Link {
+	ancestors := func(node string) []string {
+		chain := []string{node}
+		for {
+			p, ok := parents[node]
+			if !ok {
+				return chain
+			}
+			node = p.to
+			chain = append(chain, node)
+		}
+	}
+	up, down := ancestors(from), ancestors(to)
+	// both chains end with the first node of the part, they are cut to end with the closest common ancestor
+	for len(up) > 1 && len(down) > 1 && up[len(up)-2] == down[len(down)-2] {
+		up, down = up[:len(up)-1], down[:len(down)-1]
+	}
+
+	var links []Link
+	for _, node := range up[:len(up)-1] {
+		links = append(links, parents[node].link)
+	}
+	for i := len(down) - 2; i >= 0; i-- {
+		links = append(links, parents[down[i]].link)
+	}
+	return links
+}
+
+// nearestFreeCell returns the wanted cell if it is free, otherwise the closest free cell around it
+func nearestFreeCell(occupied map[Point]bool, wanted Point) Point {
+	if !occupied[wanted] {
+		return wanted
+	}
+	for r := 1; ; r++ {
+		for dy := -r; dy <= r; dy++ {
+			for dx := -r; dx <= r; dx++ {
+				if dx != -r && dx != r && dy != -r && dy != r {
+					continue
+				}
+				p := wanted.Add(Point{X: dx, Y: dy})
+				if !occupied[p] {
+					return p
+				}
+			}
+		}
+	}
+}
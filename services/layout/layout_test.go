@@ -0,0 +1,89 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	north = Point{Y: -1}
+	east  = Point{X: 1}
+	south = Point{Y: 1}
+	west  = Point{X: -1}
+)
+
+func TestComputeFollowsOffsets(t *testing.T) {
+	l := Compute([]string{"B", "A", "C"}, []Link{
+		{From: "A", To: "B", Direction: "east", Offset: east, Geometric: true},
+		{From: "B", To: "C", Direction: "north", Offset: north, Geometric: true},
+		{From: "C", To: "B", Direction: "south", Offset: south, Geometric: true},
+	})
+	require.Equal(t, map[string]Point{
+		"A": {X: 0, Y: 1},
+		"B": {X: 1, Y: 1},
+		"C": {X: 1, Y: 0},
+	}, l.Positions)
+	require.Empty(t, l.Conflicts)
+	require.Empty(t, l.Overlaps)
+}
+
+func TestComputeResolvesTakenCells(t *testing.T) {
+	links := []Link{
+		{From: "A", To: "B", Direction: "east", Offset: east, Geometric: true},
+		{From: "C", To: "B", Direction: "east", Offset: east, Geometric: true},
+	}
+	l := Compute([]string{"A", "B", "C"}, links)
+	require.Len(t, l.Positions, 3)
+	require.Equal(t, l.Positions["A"].Add(east), l.Positions["B"])
+	require.NotEqual(t, l.Positions["A"], l.Positions["C"])
+	require.Empty(t, l.Conflicts)
+	require.Equal(t, [][]string{{"A", "C"}}, l.Overlaps)
+}
+
+func TestComputePlacesNodesOfLinksWithoutGeometryNextToEachOther(t *testing.T) {
+	l := Compute([]string{"A", "B"}, []Link{{From: "A", To: "B", Direction: "portal"}})
+	offset := l.Positions["B"].Sub(l.Positions["A"])
+	require.NotEqual(t, Point{}, offset)
+	require.True(t, offset.X >= -1 && offset.X <= 1 && offset.Y >= -1 && offset.Y <= 1)
+	require.Empty(t, l.Conflicts)
+	require.Empty(t, l.Overlaps)
+}
+
+func TestComputePlacesDisconnectedPartsSideBySide(t *testing.T) {
+	l := Compute([]string{"A", "B", "C", "D", "E"}, []Link{
+		{From: "A", To: "B", Direction: "west", Offset: west, Geometric: true},
+		{From: "C", To: "D", Direction: "north", Offset: north, Geometric: true},
+	})
+	require.Equal(t, map[string]Point{
+		"A": {X: 1, Y: 0},
+		"B": {X: 0, Y: 0},
+		"C": {X: 3, Y: 1},
+		"D": {X: 3, Y: 0},
+		"E": {X: 5, Y: 0},
+	}, l.Positions)
+}
+
+func TestComputeFindsConflicts(t *testing.T) {
+	// C is north of A and also south of it via B
+	links := []Link{
+		{From: "A", To: "B", Direction: "east", Offset: east, Geometric: true},
+		{From: "A", To: "C", Direction: "north", Offset: north, Geometric: true},
+		{From: "B", To: "C", Direction: "southwest", Offset: Point{X: -1, Y: 1}, Geometric: true},
+	}
+	l := Compute([]string{"A", "B", "C"}, links)
+	require.Equal(t, []Conflict{{Link: links[2], Path: []Link{links[0], links[1]}}}, l.Conflicts)
+	require.Empty(t, l.Overlaps)
+	// every node still has its own cell
+	require.Len(t, l.Positions, 3)
+	require.NotEqual(t, l.Positions["B"], l.Positions["C"])
+}
+
+func TestComputeIgnoresUnknownNodesAndLoops(t *testing.T) {
+	l := Compute([]string{"A"}, []Link{
+		{From: "A", To: "A", Direction: "north", Offset: north, Geometric: true},
+		{From: "A", To: "Z", Direction: "east", Offset: east, Geometric: true},
+	})
+	require.Equal(t, map[string]Point{"A": {}}, l.Positions)
+	require.Empty(t, l.Conflicts)
+}
@@ -0,0 +1,220 @@
+package simulator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Built-in attributes of cities
+const (
+	// AttributePopulation is the number of people living in the city
+	AttributePopulation = "population"
+	// AttributeDefense is the number of extra aliens needed to destroy the city
+	AttributeDefense = "defense"
+	// AttributeCapacity is the maximum number of aliens which may be in the city at the same time
+	AttributeCapacity = "capacity"
+)
+
+// attributeDirective declares a custom attribute of cities in the map header, for example `@attribute airport bool`
+const attributeDirective = "@attribute"
+
+// attributeType is a type of values of an attribute
+type attributeType string
+
+const (
+	attributeInt    attributeType = "int"
+	attributeFloat  attributeType = "float"
+	attributeBool   attributeType = "bool"
+	attributeString attributeType = "string"
+)
+
+// description returns the description of values of the type for error messages
+func (t attributeType) description() string {
+	switch t {
+	case attributeInt:
+		return "an integer"
+	case attributeFloat:
+		return "a number"
+	case attributeBool:
+		return "true or false"
+	default:
+		return "a string"
+	}
+}
+
+// Attributes are typed attributes of a city, for example its population.
+// Values are int64, float64, bool or string depending on the attribute type
+type Attributes map[string]interface{}
+
+// Int returns the value of an integer attribute
+func (a Attributes) Int(name string) (int64, bool) {
+	v, ok := a[name].(int64)
+	return v, ok
+}
+
+// Float returns the value of a number attribute, integer attributes are converted to numbers
+func (a Attributes) Float(name string) (float64, bool) {
+	switch v := a[name].(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// Bool returns the value of a boolean attribute
+func (a Attributes) Bool(name string) (bool, bool) {
+	v, ok := a[name].(bool)
+	return v, ok
+}
+
+// Text returns the value of a string attribute
+func (a Attributes) Text(name string) (string, bool) {
+	v, ok := a[name].(string)
+	return v, ok
+}
+
+// copy returns a copy of attributes
+func (a Attributes) copy() Attributes {
+	if a == nil {
+		return nil
+	}
+	cp := make(Attributes, len(a))
+	for name, value := range a {
+		cp[name] = value
+	}
+	return cp
+}
+
+// attributeSchema keeps types of attributes cities of a map may have
+type attributeSchema struct {
+	// names of attributes in the order of declaration, built-in attributes go first
+	names []string
+	types map[string]attributeType
+}
+
+// builtInAttributes is the number of built-in attributes at the beginning of every schema
+const builtInAttributes = 3
+
+// newAttributeSchema creates a schema with built-in attributes
+func newAttributeSchema() *attributeSchema {
+	return &attributeSchema{
+		names: []string{AttributePopulation, AttributeDefense, AttributeCapacity},
+		types: map[string]attributeType{
+			AttributePopulation: attributeInt,
+			AttributeDefense:    attributeInt,
+			AttributeCapacity:   attributeInt,
+		},
+	}
+}
+
+// declare adds a custom attribute to the schema
+func (s *attributeSchema) declare(name, typ string) error {
+	if !isValidDirection(name) {
+		return fmt.Errorf("expected a valid attribute name, got %s", name)
+	}
+	if s.has(name) {
+		return fmt.Errorf("got attribute duplication %s", name)
+	}
+	switch t := attributeType(typ); t {
+	case attributeInt, attributeFloat, attributeBool, attributeString:
+		s.names = append(s.names, name)
+		s.types[name] = t
+		return nil
+	default:
+		return fmt.Errorf("unknown attribute type %s, expected one of int,float,bool,string", typ)
+	}
+}
+
+// has checks whether the schema has the attribute or not
+func (s *attributeSchema) has(name string) bool {
+	_, ok := s.types[name]
+	return ok
+}
+
+// custom returns names of attributes declared by the map
+func (s *attributeSchema) custom() []string {
+	return s.names[builtInAttributes:]
+}
+
+// parseValue parses the value of the attribute written in a map
+func (s *attributeSchema) parseValue(name, token string) (interface{}, error) {
+	t := s.types[name]
+	invalid := fmt.Errorf("invalid value %s of attribute %s, expected %s", token, name, t.description())
+	switch t {
+	case attributeInt:
+		v, err := strconv.ParseInt(token, 10, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		minimum := int64(0)
+		if name == AttributeCapacity {
+			minimum = 1
+		}
+		if v < minimum && s.isBuiltIn(name) {
+			return nil, fmt.Errorf("attribute %s must be at least %d, got %d", name, minimum, v)
+		}
+		return v, nil
+	case attributeFloat:
+		v, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		return v, nil
+	case attributeBool:
+		v, err := strconv.ParseBool(token)
+		if err != nil {
+			return nil, invalid
+		}
+		return v, nil
+	default:
+		if strings.HasPrefix(token, `"`) {
+			v, err := strconv.Unquote(token)
+			if err != nil {
+				return nil, invalid
+			}
+			return v, nil
+		}
+		return token, nil
+	}
+}
+
+// isBuiltIn checks whether the attribute is a built-in one or not
+func (s *attributeSchema) isBuiltIn(name string) bool {
+	for _, n := range s.names[:builtInAttributes] {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// formatAttributeValue returns the value the way it must be written in a map
+func formatAttributeValue(value interface{}) string {
+	switch v := value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return quoteCityName(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// formatAttributes returns attributes of a city in the order of the schema, every attribute is preceded by a space
+func formatAttributes(schema *attributeSchema, attributes Attributes) string {
+	builder := strings.Builder{}
+	for _, name := range schema.names {
+		if value, ok := attributes[name]; ok {
+			builder.WriteString(" " + name + "=" + formatAttributeValue(value))
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,79 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributesAreTyped(t *testing.T) {
+	a := Attributes{"population": int64(10), "area": 1.5, "airport": true, "country": "France"}
+	population, ok := a.Int("population")
+	require.True(t, ok)
+	require.Equal(t, int64(10), population)
+	_, ok = a.Int("area")
+	require.False(t, ok)
+
+	area, ok := a.Float("area")
+	require.True(t, ok)
+	require.Equal(t, 1.5, area)
+	asFloat, ok := a.Float("population")
+	require.True(t, ok)
+	require.Equal(t, 10.0, asFloat)
+
+	airport, ok := a.Bool("airport")
+	require.True(t, ok && airport)
+	country, ok := a.Text("country")
+	require.True(t, ok)
+	require.Equal(t, "France", country)
+	_, ok = Attributes(nil).Text("country")
+	require.False(t, ok)
+}
+
+func TestAttributeSchemaParsesValues(t *testing.T) {
+	schema := newAttributeSchema()
+	require.Nil(t, schema.declare("area", "float"))
+	require.Nil(t, schema.declare("airport", "bool"))
+	require.Nil(t, schema.declare("country", "string"))
+	require.Equal(t, []string{"area", "airport", "country"}, schema.custom())
+
+	testCases := []struct {
+		name, token string
+		value       interface{}
+		err         string
+	}{
+		{name: "population", token: "2100000", value: int64(2100000)},
+		{name: "population", token: "many", err: "invalid value many of attribute population, expected an integer"},
+		{name: "defense", token: "-1", err: "attribute defense must be at least 0, got -1"},
+		{name: "capacity", token: "0", err: "attribute capacity must be at least 1, got 0"},
+		{name: "area", token: "105.4", value: 105.4},
+		{name: "area", token: "big", err: "invalid value big of attribute area, expected a number"},
+		{name: "airport", token: "true", value: true},
+		{name: "airport", token: "yes", err: "invalid value yes of attribute airport, expected true or false"},
+		{name: "country", token: "France", value: "France"},
+		{name: "country", token: `"United States"`, value: "United States"},
+	}
+	for _, tc := range testCases {
+		value, err := schema.parseValue(tc.name, tc.token)
+		if tc.err != "" {
+			require.EqualError(t, err, tc.err, tc.token)
+			continue
+		}
+		require.Nil(t, err, tc.token)
+		require.Equal(t, tc.value, value, tc.token)
+	}
+}
+
+func TestAttributeSchemaRejectsInvalidDeclarations(t *testing.T) {
+	schema := newAttributeSchema()
+	require.EqualError(t, schema.declare("population", "int"), "got attribute duplication population")
+	require.EqualError(t, schema.declare("area", "decimal"), "unknown attribute type decimal, expected one of int,float,bool,string")
+	require.EqualError(t, schema.declare("1st", "int"), "expected a valid attribute name, got 1st")
+}
+
+func TestFormatAttributesFollowsSchemaOrder(t *testing.T) {
+	schema := newAttributeSchema()
+	require.Nil(t, schema.declare("country", "string"))
+	attributes := Attributes{"country": "United States", "defense": int64(3), "population": int64(100)}
+	require.Equal(t, ` population=100 defense=3 country="United States"`, formatAttributes(schema, attributes))
+}
@@ -0,0 +1,170 @@
+package simulator
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// BatchConfig contains parameters of a Monte Carlo batch of simulations
+type BatchConfig struct {
+	// Configuration of every simulation of the batch
+	Simulation SimulationConfig
+
+	// Number of simulations in the batch
+	Runs int
+
+	// Number of simulations running in parallel
+	Workers int
+
+	// Seed from which seeds of every simulation are derived
+	Seed int64
+}
+
+// Validate checks that configuration values make sense
+func (c BatchConfig) Validate() error {
+	if c.Runs <= 0 {
+		return fmt.Errorf("number of runs must be positive, got %d", c.Runs)
+	}
+	if c.Workers <= 0 {
+		return fmt.Errorf("number of workers must be positive, got %d", c.Workers)
+	}
+	return c.Simulation.Validate()
+}
+
+// CityDestruction is the probability of a city to be destroyed during an invasion
+type CityDestruction struct {
+	City        string  `json:"city"`
+	Probability float64 `json:"probability"`
+}
+
+// BatchResult contains aggregated results of a batch of simulations
+type BatchResult struct {
+	Runs int `json:"runs"`
+
+	// Destruction probability of every city sorted from the most to the least endangered city
+	CityDestruction []CityDestruction `json:"cityDestruction"`
+
+	// Number of runs for every number of surviving cities
+	SurvivingCities map[int]int `json:"survivingCities"`
+
+	// Average number of turns before the simulation is over
+	MeanTurns float64 `json:"meanTurns"`
+
+	// Average total population of destroyed cities
+	MeanDestroyedPopulation float64 `json:"meanDestroyedPopulation"`
+
+	// Number of runs for every reason of the simulation end
+	EndReasons map[EndReason]int `json:"endReasons"`
+}
+
+// runSummary is the part of a simulation result needed for aggregation
+type runSummary struct {
+	destroyed []string
+	surviving int
+	turns     int
+	reason    EndReason
+	err       error
+}
+
+// deriveSeeds returns seeds of every simulation in the batch. The seed of a simulation depends only
+// on the batch seed and the simulation index, so results don't depend on the number of workers
+func deriveSeeds(seed int64, runs int) []int64 {
+	rnd := rand.New(rand.NewSource(seed))
+	seeds := make([]int64, runs)
+	for i := range seeds {
+		seeds[i] = rnd.Int63()
+	}
+	return seeds
+}
+
+// RunBatch runs the configured number of simulations in parallel and aggregates their results.
+// Strategies of the simulation config are shared between workers and must be safe for concurrent use
+func (s *Simulation) RunBatch(cfg BatchConfig) (*BatchResult, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	// only final states of simulations are aggregated
+	cfg.Simulation.DiscardEvents = true
+	seeds := deriveSeeds(cfg.Seed, cfg.Runs)
+	summaries := make([]runSummary, cfg.Runs)
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < cfg.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				// every run gets its own source of randomness, the initial map is only read by runs
+				run := &Simulation{initialMap: s.initialMap, topology: s.topology, schema: s.schema, source: rand.NewSource(seeds[i])}
+				result, err := run.Run(cfg.Simulation)
+				if err != nil {
+					summaries[i].err = err
+					continue
+				}
+				summaries[i] = runSummary{
+					destroyed: result.DestroyedCities(),
+					surviving: len(result.SurvivingCities()),
+					turns:     result.Turns,
+					reason:    result.EndReason,
+				}
+			}
+		}()
+	}
+	for i := 0; i < cfg.Runs; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return s.aggregate(summaries)
+}
+
+// aggregate aggregates summaries of simulations in the order of their indexes
+func (s *Simulation) aggregate(summaries []runSummary) (*BatchResult, error) {
+	res := &BatchResult{
+		Runs:            len(summaries),
+		SurvivingCities: map[int]int{},
+		EndReasons:      map[EndReason]int{},
+	}
+	destructions := make(map[string]int, len(s.initialMap))
+	for name := range s.initialMap {
+		destructions[name] = 0
+	}
+	totalTurns := 0
+	for i, summary := range summaries {
+		if summary.err != nil {
+			return nil, fmt.Errorf("simulation %d failed: %w", i, summary.err)
+		}
+		for _, name := range summary.destroyed {
+			destructions[name]++
+		}
+		res.SurvivingCities[summary.surviving]++
+		res.EndReasons[summary.reason]++
+		totalTurns += summary.turns
+	}
+	res.MeanTurns = float64(totalTurns) / float64(len(summaries))
+
+	res.CityDestruction = make([]CityDestruction, 0, len(destructions))
+	for name, count := range destructions {
+		res.CityDestruction = append(res.CityDestruction, CityDestruction{
+			City:        name,
+			Probability: float64(count) / float64(len(summaries)),
+		})
+	}
+	sort.Slice(res.CityDestruction, func(i, j int) bool {
+		a, b := res.CityDestruction[i], res.CityDestruction[j]
+		if a.Probability != b.Probability {
+			return a.Probability > b.Probability
+		}
+		return a.City < b.City
+	})
+	// cities are summed up in the sorted order, so the result doesn't depend on the map iteration order
+	for _, d := range res.CityDestruction {
+		population, _ := s.initialMap[d.City].attributes.Int(AttributePopulation)
+		res.MeanDestroyedPopulation += d.Probability * float64(population)
+	}
+	return res, nil
+}
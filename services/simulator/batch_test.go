@@ -0,0 +1,89 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchConfigValidation(t *testing.T) {
+	cfg := BatchConfig{Simulation: DefaultSimulationConfig(10), Runs: 10, Workers: 2}
+	require.Nil(t, cfg.Validate())
+	cfg.Runs = 0
+	require.EqualError(t, cfg.Validate(), "number of runs must be positive, got 0")
+	cfg.Runs, cfg.Workers = 10, 0
+	require.EqualError(t, cfg.Validate(), "number of workers must be positive, got 0")
+	cfg.Workers, cfg.Simulation.DestructionThreshold = 2, 1
+	require.EqualError(t, cfg.Validate(), "destruction threshold must be at least 2, got 1")
+}
+
+func TestBatchDoesNotDependOnNumberOfWorkers(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	cfg := BatchConfig{Simulation: DefaultSimulationConfig(8), Runs: 300, Workers: 1, Seed: 42}
+	sequential, err := s.RunBatch(cfg)
+	require.Nil(t, err)
+	cfg.Workers = 8
+	parallel, err := s.RunBatch(cfg)
+	require.Nil(t, err)
+	require.Equal(t, sequential, parallel)
+}
+
+func TestBatchAggregatesRuns(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	cfg := BatchConfig{Simulation: DefaultSimulationConfig(8), Runs: 200, Workers: 4, Seed: 7}
+	res, err := s.RunBatch(cfg)
+	require.Nil(t, err)
+
+	require.Equal(t, 200, res.Runs)
+	require.Len(t, res.CityDestruction, len(s.initialMap))
+	for i, d := range res.CityDestruction {
+		require.GreaterOrEqual(t, d.Probability, 0.0)
+		require.LessOrEqual(t, d.Probability, 1.0)
+		if i > 0 {
+			require.GreaterOrEqual(t, res.CityDestruction[i-1].Probability, d.Probability)
+		}
+	}
+	runs := 0
+	for surviving, count := range res.SurvivingCities {
+		require.LessOrEqual(t, surviving, len(s.initialMap))
+		runs += count
+	}
+	require.Equal(t, 200, runs)
+	runs = 0
+	for _, count := range res.EndReasons {
+		runs += count
+	}
+	require.Equal(t, 200, runs)
+	require.Greater(t, res.MeanTurns, 0.0)
+
+	// every seed derived by the batch gives the same result when simulated alone
+	seeds := deriveSeeds(7, 200)
+	for _, i := range []int{0, 99, 199} {
+		s.SetSeed(seeds[i])
+		single, err := s.Run(cfg.Simulation)
+		require.Nil(t, err)
+		require.Greater(t, res.EndReasons[single.EndReason], 0)
+	}
+}
+
+func TestBatchReportsSimulationErrors(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	cfg := BatchConfig{Simulation: DefaultSimulationConfig(8), Runs: 3, Workers: 2}
+	cfg.Simulation.Spawn = ListSpawn{Cities: []string{"Atlantis"}}
+	_, err = s.RunBatch(cfg)
+	require.EqualError(t, err, "simulation 0 failed: failed to spawn aliens: cannot spawn aliens in non existent city Atlantis")
+}
+
+func TestBatchWeightsDestructionByPopulation(t *testing.T) {
+	s, err := createSimulation(strings.NewReader("Paris population=1000 north=Berlin\nBerlin population=10 south=Paris\n"), "testing")
+	require.Nil(t, err)
+	cfg := BatchConfig{Simulation: DefaultSimulationConfig(2), Runs: 10, Workers: 2, Seed: 1}
+	cfg.Simulation.Spawn = ListSpawn{Cities: []string{"Paris"}}
+	res, err := s.RunBatch(cfg)
+	require.Nil(t, err)
+	require.Equal(t, 1000.0, res.MeanDestroyedPopulation)
+}
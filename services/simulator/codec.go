@@ -0,0 +1,205 @@
+package simulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Names of map formats
+const (
+	FormatEmap = "emap"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// MapCodec reads and writes maps in a file format
+type MapCodec interface {
+	// Name returns the name of the format
+	Name() string
+	// Decode reads a map and checks it the same way as a map is checked before a simulation.
+	// All problems of the map are returned together as MapErrors
+	Decode(src io.Reader, filename string) (*MapDocument, error)
+	// Encode checks the map and writes it in the format
+	Encode(w io.Writer, doc *MapDocument) error
+}
+
+// documentCodec is a codec of a format which encodes map documents directly
+type documentCodec interface {
+	MapCodec
+	// unmarshal reads a map document without checking it
+	unmarshal(src io.Reader, filename string) (*MapDocument, error)
+}
+
+// EmapCodec reads and writes maps in the .emap text format
+type EmapCodec struct{}
+
+func (EmapCodec) Name() string {
+	return FormatEmap
+}
+
+func (EmapCodec) Decode(src io.Reader, filename string) (*MapDocument, error) {
+	p := newParser(src, filename)
+	if err := p.parseWithOptions(ParseOptions{}); err != nil {
+		return nil, err
+	}
+	return p.document(), nil
+}
+
+// Encode writes the map in the canonical format
+func (EmapCodec) Encode(w io.Writer, doc *MapDocument) error {
+	p, err := doc.parse("", ParseOptions{})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(p.format())
+	return err
+}
+
+// JSONCodec reads and writes maps as JSON map documents
+type JSONCodec struct{}
+
+func (JSONCodec) Name() string {
+	return FormatJSON
+}
+
+func (c JSONCodec) Decode(src io.Reader, filename string) (*MapDocument, error) {
+	return decodeDocument(c, src, filename)
+}
+
+func (JSONCodec) Encode(w io.Writer, doc *MapDocument) error {
+	p, err := doc.parse("", ParseOptions{})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p.document())
+}
+
+// unmarshal reads a JSON document, numbers are kept as they are written, so integer attributes don't lose precision
+func (JSONCodec) unmarshal(src io.Reader, filename string) (*MapDocument, error) {
+	dec := json.NewDecoder(src)
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	doc := &MapDocument{}
+	if err := dec.Decode(doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return doc, nil
+}
+
+// YAMLCodec reads and writes maps as YAML map documents
+type YAMLCodec struct{}
+
+func (YAMLCodec) Name() string {
+	return FormatYAML
+}
+
+func (c YAMLCodec) Decode(src io.Reader, filename string) (*MapDocument, error) {
+	return decodeDocument(c, src, filename)
+}
+
+func (YAMLCodec) Encode(w io.Writer, doc *MapDocument) error {
+	p, err := doc.parse("", ParseOptions{})
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(p.document()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func (YAMLCodec) unmarshal(src io.Reader, filename string) (*MapDocument, error) {
+	dec := yaml.NewDecoder(src)
+	dec.KnownFields(true)
+	doc := &MapDocument{}
+	if err := dec.Decode(doc); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return doc, nil
+}
+
+// decodeDocument reads a map document and checks it with the map parser.
+// The returned document has normalized city names, typed attribute values and roads in the order of the topology
+func decodeDocument(c documentCodec, src io.Reader, filename string) (*MapDocument, error) {
+	doc, err := c.unmarshal(src, filename)
+	if err != nil {
+		return nil, err
+	}
+	p, err := doc.parse(filename, ParseOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return p.document(), nil
+}
+
+// MapCodecByName returns the codec of the format with the name
+func MapCodecByName(name string) (MapCodec, error) {
+	switch strings.ToLower(name) {
+	case FormatEmap:
+		return EmapCodec{}, nil
+	case FormatJSON:
+		return JSONCodec{}, nil
+	case FormatYAML, "yml":
+		return YAMLCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown map format %s, expected one of emap,json,yaml", name)
+	}
+}
+
+// yamlLine is the first line of a YAML map document
+var yamlLine = regexp.MustCompile(`^(---|(topology|attributes|cities)\s*:)`)
+
+// DetectMapCodec returns the codec of the map file by its extension: .emap, .json, .yaml or .yml.
+// Files with other extensions are recognized by their content, the .emap format is the default one
+func DetectMapCodec(filename string, content []byte) MapCodec {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".emap":
+		return EmapCodec{}
+	case ".json":
+		return JSONCodec{}
+	case ".yaml", ".yml":
+		return YAMLCodec{}
+	}
+	// the first line which is neither blank nor a comment tells the format
+	rest := bytes.TrimLeft(content, " \t\r\n")
+	for len(rest) != 0 && rest[0] == commentSign {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			return EmapCodec{}
+		}
+		rest = bytes.TrimLeft(rest[i+1:], " \t\r\n")
+	}
+	switch {
+	case bytes.HasPrefix(rest, []byte("{")):
+		return JSONCodec{}
+	case yamlLine.Match(rest):
+		return YAMLCodec{}
+	default:
+		return EmapCodec{}
+	}
+}
+
+// createSimulationFromSource creates simulation from a map file in any supported format.
+// Errors of .emap files keep their positions, errors of map documents have paths in the document
+func createSimulationFromSource(src []byte, filename string, opts ParseOptions) (*Simulation, error) {
+	codec, ok := DetectMapCodec(filename, src).(documentCodec)
+	if !ok {
+		return createSimulationWithOptions(bytes.NewReader(src), filename, opts)
+	}
+	doc, err := codec.unmarshal(bytes.NewReader(src), filename)
+	if err != nil {
+		return nil, err
+	}
+	return createSimulationFromDocument(doc, filename, opts)
+}
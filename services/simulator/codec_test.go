@@ -0,0 +1,155 @@
+package simulator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const codecTestMap = `@topology hex
+@attribute airport bool
+Paris population=2100000 airport=true northeast=Berlin west="San José":3
+Berlin southwest=Paris
+"San José" east=Paris:3
+`
+
+const codecTestJSON = `{
+  "topology": "hex",
+  "attributes": [{"name": "airport", "type": "bool"}],
+  "cities": [
+    {
+      "name": "Paris",
+      "attributes": {"population": 2100000, "airport": true},
+      "roads": [
+        {"direction": "west", "city": "San José", "weight": 3},
+        {"direction": "northeast", "city": "Berlin"}
+      ]
+    },
+    {"name": "Berlin", "roads": [{"direction": "southwest", "city": "Paris"}]},
+    {"name": "San José", "roads": [{"direction": "east", "city": "Paris", "weight": 3}]}
+  ]
+}`
+
+const codecTestYAML = `# the same map in YAML
+topology: hex
+attributes:
+  - name: airport
+    type: bool
+cities:
+  - name: Paris
+    attributes: {population: 2100000, airport: true}
+    roads:
+      - {direction: west, city: San José, weight: 3}
+      - {direction: northeast, city: Berlin}
+  - name: Berlin
+    roads: [{direction: southwest, city: Paris}]
+  - name: San José
+    roads: [{direction: east, city: Paris, weight: 3}]
+`
+
+func TestDetectMapCodec(t *testing.T) {
+	require.Equal(t, FormatEmap, DetectMapCodec("earth.emap", []byte(codecTestJSON)).Name())
+	require.Equal(t, FormatJSON, DetectMapCodec("earth.JSON", nil).Name())
+	require.Equal(t, FormatYAML, DetectMapCodec("earth.yml", nil).Name())
+	require.Equal(t, FormatYAML, DetectMapCodec("earth.yaml", nil).Name())
+
+	// files with other extensions are recognized by their content
+	require.Equal(t, FormatJSON, DetectMapCodec("earth", []byte(codecTestJSON)).Name())
+	require.Equal(t, FormatYAML, DetectMapCodec("earth.map", []byte(codecTestYAML)).Name())
+	require.Equal(t, FormatYAML, DetectMapCodec("earth", []byte("---\ncities: []\n")).Name())
+	require.Equal(t, FormatEmap, DetectMapCodec("earth.txt", []byte(codecTestMap)).Name())
+	require.Equal(t, FormatEmap, DetectMapCodec("earth", []byte("# only a comment")).Name())
+	require.Equal(t, FormatEmap, DetectMapCodec("-", nil).Name())
+}
+
+func TestMapCodecByName(t *testing.T) {
+	for _, name := range []string{"emap", "json", "yaml", "yml", "JSON"} {
+		codec, err := MapCodecByName(name)
+		require.Nil(t, err)
+		require.NotNil(t, codec)
+	}
+	_, err := MapCodecByName("xml")
+	require.EqualError(t, err, "unknown map format xml, expected one of emap,json,yaml")
+}
+
+func TestCodecsDecodeTheSameMap(t *testing.T) {
+	expected, err := EmapCodec{}.Decode(strings.NewReader(codecTestMap), "testing.emap")
+	require.Nil(t, err)
+	require.Equal(t, "hex", expected.Topology)
+	require.Equal(t, []AttributeDocument{{Name: "airport", Type: "bool"}}, expected.Attributes)
+	require.Equal(t, Attributes{AttributePopulation: int64(2100000), "airport": true}, expected.Cities[0].Attributes)
+	require.Equal(t, []RoadDocument{
+		{Direction: "northeast", City: "Berlin"},
+		{Direction: "west", City: "San José", Weight: 3},
+	}, expected.Cities[0].Roads)
+
+	doc, err := JSONCodec{}.Decode(strings.NewReader(codecTestJSON), "testing.json")
+	require.Nil(t, err)
+	require.Equal(t, expected, doc)
+
+	doc, err = YAMLCodec{}.Decode(strings.NewReader(codecTestYAML), "testing.yaml")
+	require.Nil(t, err)
+	require.Equal(t, expected, doc)
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	expected, err := EmapCodec{}.Decode(strings.NewReader(codecTestMap), "testing.emap")
+	require.Nil(t, err)
+	for _, codec := range []MapCodec{EmapCodec{}, JSONCodec{}, YAMLCodec{}} {
+		buf := bytes.Buffer{}
+		require.Nil(t, codec.Encode(&buf, expected), codec.Name())
+		require.Equal(t, codec.Name(), DetectMapCodec("testing", buf.Bytes()).Name())
+		doc, err := codec.Decode(&buf, "testing")
+		require.Nil(t, err, codec.Name())
+		require.Equal(t, expected, doc, codec.Name())
+	}
+}
+
+func TestEmapCodecEncodesCanonicalMap(t *testing.T) {
+	doc, err := JSONCodec{}.Decode(strings.NewReader(codecTestJSON), "testing.json")
+	require.Nil(t, err)
+	buf := bytes.Buffer{}
+	require.Nil(t, EmapCodec{}.Encode(&buf, doc))
+	require.Equal(t, codecTestMap, buf.String())
+}
+
+func TestDocumentCodecsReportProblemsWithPaths(t *testing.T) {
+	input := `{"cities": [
+  {"name": "Paris", "roads": [{"direction": "up", "city": "Rome"}, {"direction": "south", "city": "Atlantis"}]},
+  {"name": "Rome", "attributes": {"population": "many"}, "roads": [{"direction": "north", "city": "Paris"}]},
+  {"name": "Oslo", "roads": [{"direction": "north", "city": "Paris", "weight": -1}]}
+]}`
+	_, err := JSONCodec{}.Decode(strings.NewReader(input), "testing.json")
+	require.EqualError(t, err, `testing.json: cities[0]: got unexpected mapDirection type up, expected one of south,north,west,east
+testing.json: cities[1]: invalid value "many" of attribute population, expected an integer
+testing.json: cities[2]: road weight must be a positive integer, got -1`)
+
+	// roads to non existent cities are checked only when the map has no other problems
+	_, err = YAMLCodec{}.Decode(strings.NewReader("cities:\n  - {name: Paris, roads: [{direction: south, city: Atlantis}]}\n"), "testing.yaml")
+	require.EqualError(t, err, "testing.yaml: cities[0]: city Paris has direction south which points to non existent city Atlantis")
+}
+
+func TestDocumentCodecsRejectUnknownFields(t *testing.T) {
+	_, err := JSONCodec{}.Decode(strings.NewReader(`{"cities": [], "planet": "Earth"}`), "testing.json")
+	require.EqualError(t, err, `testing.json: json: unknown field "planet"`)
+
+	_, err = YAMLCodec{}.Decode(strings.NewReader("planet: Earth\n"), "testing.yaml")
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "testing.yaml: "), err.Error())
+}
+
+func TestCreateSimulationFromSourceDetectsFormat(t *testing.T) {
+	expected, err := createSimulation(strings.NewReader(codecTestMap), "testing")
+	require.Nil(t, err)
+	for _, src := range []string{codecTestMap, codecTestJSON, codecTestYAML} {
+		s, err := createSimulationFromSource([]byte(src), "testing", ParseOptions{})
+		require.Nil(t, err)
+		require.Equal(t, expected, s)
+	}
+
+	s, err := createSimulationFromSource([]byte(`{"cities": [{"name": "A", "roads": [{"direction": "north", "city": "B"}]}, {"name": "B", "roads": [{"direction": "east", "city": "C"}]}, {"name": "C", "roads": [{"direction": "west", "city": "B"}]}]}`), "testing", ParseOptions{InferReverseRoads: true})
+	require.Nil(t, err)
+	require.Equal(t, mapDirection{directionType: directionSouth, directionValue: "A"}, s.initialMap["B"].directions[0])
+}
@@ -0,0 +1,71 @@
+package simulator
+
+import "fmt"
+
+const (
+	defaultMaxTurns             = 10000
+	defaultDestructionThreshold = 2
+	minDestructionThreshold     = 2
+)
+
+// SimulationConfig contains parameters of a single simulation run
+type SimulationConfig struct {
+	// Number of aliens which invade the planet
+	Aliens int64
+
+	// Maximum number of turns, the simulation is over when all turns are finished
+	MaxTurns int
+
+	// Number of aliens which destroy a city when they meet in it
+	DestructionThreshold int
+
+	// Decides where aliens land, if nil aliens land uniformly at random
+	Spawn SpawnStrategy
+
+	// Decides where aliens move, if nil aliens walk randomly
+	Movement MovementStrategy
+
+	// Receives events of the simulation as soon as they happen, may be nil
+	Sink EventSink
+
+	// Don't keep events in the simulation result, the sink still receives them.
+	// Saves memory when only the final state of the simulation matters
+	DiscardEvents bool
+}
+
+// DefaultSimulationConfig returns configuration of a simulation with the provided number of aliens
+// and default values of all other parameters
+func DefaultSimulationConfig(numberOfAliens int64) SimulationConfig {
+	return SimulationConfig{
+		Aliens:               numberOfAliens,
+		MaxTurns:             defaultMaxTurns,
+		DestructionThreshold: defaultDestructionThreshold,
+		Spawn:                UniformSpawn{},
+		Movement:             RandomWalk{},
+	}
+}
+
+// Validate checks that configuration values make sense
+func (c SimulationConfig) Validate() error {
+	if c.Aliens < 0 {
+		return fmt.Errorf("number of aliens must not be negative, got %d", c.Aliens)
+	}
+	if c.MaxTurns <= 0 {
+		return fmt.Errorf("max turns must be positive, got %d", c.MaxTurns)
+	}
+	if c.DestructionThreshold < minDestructionThreshold {
+		return fmt.Errorf("destruction threshold must be at least %d, got %d", minDestructionThreshold, c.DestructionThreshold)
+	}
+	return nil
+}
+
+// withDefaults returns the configuration with default strategies in place of missing ones
+func (c SimulationConfig) withDefaults() SimulationConfig {
+	if c.Spawn == nil {
+		c.Spawn = UniformSpawn{}
+	}
+	if c.Movement == nil {
+		c.Movement = RandomWalk{}
+	}
+	return c
+}
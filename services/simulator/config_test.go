@@ -0,0 +1,62 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulationConfigValidation(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(c *SimulationConfig)
+		err    string
+	}{
+		{name: "default", modify: func(c *SimulationConfig) {}},
+		{name: "no aliens", modify: func(c *SimulationConfig) { c.Aliens = 0 }},
+		{name: "negative aliens", modify: func(c *SimulationConfig) { c.Aliens = -1 }, err: "number of aliens must not be negative, got -1"},
+		{name: "zero turns", modify: func(c *SimulationConfig) { c.MaxTurns = 0 }, err: "max turns must be positive, got 0"},
+		{name: "negative turns", modify: func(c *SimulationConfig) { c.MaxTurns = -5 }, err: "max turns must be positive, got -5"},
+		{name: "threshold below 2", modify: func(c *SimulationConfig) { c.DestructionThreshold = 1 }, err: "destruction threshold must be at least 2, got 1"},
+		{name: "higher threshold", modify: func(c *SimulationConfig) { c.DestructionThreshold = 5 }},
+		{name: "no strategies", modify: func(c *SimulationConfig) { c.Spawn, c.Movement = nil, nil }},
+	}
+	for _, tc := range cases {
+		cfg := DefaultSimulationConfig(10)
+		tc.modify(&cfg)
+		err := cfg.Validate()
+		if tc.err == "" {
+			require.Nil(t, err, tc.name)
+			continue
+		}
+		require.EqualError(t, err, tc.err, tc.name)
+	}
+}
+
+func TestSimulationRejectsInvalidConfig(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	_, err = s.Run(SimulationConfig{Aliens: 2, MaxTurns: -1, DestructionThreshold: 2})
+	require.EqualError(t, err, "max turns must be positive, got -1")
+}
+
+func TestSimulationFollowsConfig(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	s.SetSeed(1)
+	// three aliens in Paris are not enough to destroy it with threshold 4, all aliens stay forever
+	res, err := s.Run(SimulationConfig{
+		Aliens:               3,
+		MaxTurns:             5,
+		DestructionThreshold: 4,
+		Spawn:                ListSpawn{Cities: []string{"Paris"}},
+		Movement:             StayPut{Probability: 1},
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"Simulate invasion with 3 aliens",
+		"5 turns are finished. Simulation is over",
+	}, res.Logs())
+	require.False(t, res.ResultMap["Paris"].isDestroyed)
+}
@@ -0,0 +1,220 @@
+package simulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/scanner"
+)
+
+// MapDocument is a map in a format independent form, JSON and YAML maps are encoded map documents
+type MapDocument struct {
+	// Topology is a topology specification which ParseTopology accepts, the default topology if it is empty
+	Topology string `json:"topology,omitempty" yaml:"topology,omitempty"`
+	// Attributes are custom attributes cities of the map may have
+	Attributes []AttributeDocument `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	Cities     []CityDocument      `json:"cities" yaml:"cities"`
+}
+
+// AttributeDocument declares a custom attribute, Type is one of int, float, bool or string
+type AttributeDocument struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+}
+
+// CityDocument describes a city, its attributes and roads which lead out of it
+type CityDocument struct {
+	Name       string         `json:"name" yaml:"name"`
+	Attributes Attributes     `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	Roads      []RoadDocument `json:"roads" yaml:"roads"`
+}
+
+// RoadDocument describes a road, a road without a weight takes one turn
+type RoadDocument struct {
+	Direction string `json:"direction" yaml:"direction"`
+	City      string `json:"city" yaml:"city"`
+	Weight    int    `json:"weight,omitempty" yaml:"weight,omitempty"`
+}
+
+// CreateSimulationFromDocument creates simulation from a map document.
+// The document is checked the same way as a map file, all problems are returned together as MapErrors
+func CreateSimulationFromDocument(doc *MapDocument, opts ParseOptions) (*Simulation, error) {
+	return createSimulationFromDocument(doc, "", opts)
+}
+
+// createSimulationFromDocument creates simulation from a map document read from the file
+func createSimulationFromDocument(doc *MapDocument, filename string, opts ParseOptions) (*Simulation, error) {
+	p, err := doc.parse(filename, opts)
+	if err != nil {
+		return nil, err
+	}
+	return p.buildSimulation()
+}
+
+// parse checks the document with the map parser. The document is written in the map format line by line,
+// so problems are reported with paths to parts of the document instead of positions in the file
+func (d *MapDocument) parse(filename string, opts ParseOptions) (*parser, error) {
+	src, paths, err := d.render(filename)
+	if err != nil {
+		return nil, err
+	}
+	p := newParser(bytes.NewReader(src), filename)
+	if err := p.parseWithOptions(opts); err != nil {
+		return nil, remapErrors(err, filename, paths)
+	}
+	return p, nil
+}
+
+// render writes the document in the map format and returns the path to the part of the document of every line.
+// Names which can't be written as single tokens are returned as MapErrors
+func (d *MapDocument) render(filename string) ([]byte, []string, error) {
+	var errs MapErrors
+	invalid := func(path, msg string) {
+		errs = append(errs, newParserError(scanner.Position{Filename: filename}, path+": "+msg))
+	}
+	buf := bytes.Buffer{}
+	var paths []string
+	writeLine := func(path, line string) {
+		buf.WriteString(line + "\n")
+		paths = append(paths, path)
+	}
+
+	if d.Topology != "" {
+		if strings.ContainsAny(d.Topology, "\r\n#") {
+			invalid("topology", fmt.Sprintf("invalid topology %q", d.Topology))
+		} else {
+			writeLine("topology", topologyDirective+" "+d.Topology)
+		}
+	}
+	for i, a := range d.Attributes {
+		path := fmt.Sprintf("attributes[%d]", i)
+		if !isDocumentToken(a.Name) || !isDocumentToken(a.Type) {
+			invalid(path, fmt.Sprintf("expected a valid attribute name and type, got %q %q", a.Name, a.Type))
+			continue
+		}
+		writeLine(path, attributeDirective+" "+a.Name+" "+a.Type)
+	}
+	for i, c := range d.Cities {
+		path := fmt.Sprintf("cities[%d]", i)
+		builder := strings.Builder{}
+		builder.WriteString(quoteCityName(c.Name))
+
+		names := make([]string, 0, len(c.Attributes))
+		for name := range c.Attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !isDocumentToken(name) {
+				invalid(path, fmt.Sprintf("expected a valid attribute name, got %q", name))
+				continue
+			}
+			value, err := documentValue(c.Attributes[name])
+			if err != nil {
+				invalid(path, fmt.Sprintf("attribute %s %s", name, err))
+				continue
+			}
+			builder.WriteString(" " + name + "=" + value)
+		}
+
+		for _, r := range c.Roads {
+			if !isDocumentToken(r.Direction) {
+				invalid(path, fmt.Sprintf("expected a valid direction name, got %q", r.Direction))
+				continue
+			}
+			builder.WriteString(" " + r.Direction + "=" + quoteCityName(r.City))
+			if r.Weight != 0 {
+				builder.WriteString(fmt.Sprintf("%c%d", roadWeightSign, r.Weight))
+			}
+		}
+		writeLine(path, builder.String())
+	}
+	return buf.Bytes(), paths, errs.err()
+}
+
+// isDocumentToken checks whether the name can be written in the map format as a single token
+func isDocumentToken(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\r\n#=\":")
+}
+
+// documentValue returns the attribute value of a document the way it must be written in a map.
+// Strings are always quoted, so a string given for a number attribute is reported as an invalid value
+func documentValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.String(), nil
+	case string:
+		return strconv.Quote(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case nil:
+		return "", errors.New("has no value")
+	default:
+		return "", fmt.Errorf("has unsupported value %v, expected a number, a boolean or a string", v)
+	}
+}
+
+// remapErrors replaces positions of errors in the written document with paths to parts of the document
+func remapErrors(err error, filename string, paths []string) error {
+	var errs MapErrors
+	for _, e := range collectErrors(nil, err) {
+		var pe parserError
+		if errors.As(e, &pe) {
+			if path, ok := documentPath(paths, pe.position); ok {
+				e = newParserError(scanner.Position{Filename: filename}, path+": "+pe.message)
+			}
+		}
+		errs = append(errs, e)
+	}
+	return errs.err()
+}
+
+// documentPath returns the path to the part of the document written on the line of the position
+func documentPath(paths []string, position scanner.Position) (string, bool) {
+	if position.Line < 1 || position.Line > len(paths) {
+		return "", false
+	}
+	return paths[position.Line-1], true
+}
+
+// document returns the parsed map as a map document, comments and blank lines are not kept
+func (p *parser) document() *MapDocument {
+	doc := &MapDocument{Cities: []CityDocument{}}
+	if p.topologyLine != 0 {
+		doc.Topology = p.topology.String()
+	}
+	for _, name := range p.schema.custom() {
+		doc.Attributes = append(doc.Attributes, AttributeDocument{Name: name, Type: string(p.schema.types[name])})
+	}
+	for _, l := range p.layout {
+		if l.kind != layoutCity {
+			continue
+		}
+		pc := p.parsedCities[l.text]
+		c := CityDocument{Name: l.text, Roads: []RoadDocument{}}
+		if len(pc.attributes) != 0 {
+			c.Attributes = pc.attributes.copy()
+		}
+		for _, d := range p.topology.order {
+			if value := pc.getDirection(d); value != "" {
+				r := RoadDocument{Direction: d, City: value}
+				if transit := pc.transit(d); transit > 0 {
+					r.Weight = transit + 1
+				}
+				c.Roads = append(c.Roads, r)
+			}
+		}
+		doc.Cities = append(doc.Cities, c)
+	}
+	return doc
+}
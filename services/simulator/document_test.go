@@ -0,0 +1,67 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSimulationFromDocument(t *testing.T) {
+	doc := &MapDocument{Cities: []CityDocument{
+		{Name: "London", Roads: []RoadDocument{{Direction: directionEast, City: "Bolton"}}},
+		{Name: "Bolton", Roads: []RoadDocument{{Direction: directionWest, City: "London"}}},
+	}}
+	s, err := CreateSimulationFromDocument(doc, ParseOptions{})
+	require.Nil(t, err)
+	expected, err := createSimulation(strings.NewReader("London east=Bolton\nBolton west=London\n"), "testing")
+	require.Nil(t, err)
+	require.Equal(t, expected, s)
+}
+
+func TestMapDocumentRejectsNamesWhichAreNotTokens(t *testing.T) {
+	doc := &MapDocument{
+		Topology:   "hex # comment",
+		Attributes: []AttributeDocument{{Name: "has airport", Type: "bool"}},
+		Cities: []CityDocument{
+			{Name: "London", Attributes: Attributes{"tags": []interface{}{"capital"}, "rank": nil}, Roads: []RoadDocument{{Direction: "north east", City: "Bolton"}}},
+		},
+	}
+	_, err := CreateSimulationFromDocument(doc, ParseOptions{})
+	require.EqualError(t, err, `topology: invalid topology "hex # comment"
+attributes[0]: expected a valid attribute name and type, got "has airport" "bool"
+cities[0]: attribute rank has no value
+cities[0]: attribute tags has unsupported value [capital], expected a number, a boolean or a string
+cities[0]: expected a valid direction name, got "north east"`)
+}
+
+func TestMapDocumentQuotesCityNames(t *testing.T) {
+	doc := &MapDocument{Cities: []CityDocument{
+		{Name: "New York", Roads: []RoadDocument{{Direction: directionEast, City: "Paris=Rome"}}},
+		{Name: "Paris=Rome", Roads: []RoadDocument{{Direction: directionWest, City: "New York"}}},
+	}}
+	_, err := CreateSimulationFromDocument(doc, ParseOptions{})
+	require.EqualError(t, err, `cities[0]: expected a valid city name as a mapDirection value, got "Paris=Rome"
+cities[1]: expected a valid city name, got "Paris=Rome"`)
+}
+
+func TestLintDocumentReportsFindingsWithPaths(t *testing.T) {
+	input := `{"cities": [
+  {"name": "Paris", "roads": [{"direction": "south", "city": "Rome"}]},
+  {"name": "Rome", "roads": [{"direction": "north", "city": "Rome"}]}
+]}`
+	findings := lintDocument(JSONCodec{}, strings.NewReader(input), "testing.json")
+	var messages []string
+	for _, f := range findings {
+		messages = append(messages, f.String())
+	}
+	require.Equal(t, []string{
+		"testing.json: info: cities[0]: no road leads to city Paris, aliens can only land in it [unreachable-city]",
+		"testing.json: warning: cities[0]: city Paris has south=Rome, but Rome has north=Rome [asymmetric-road]",
+		"testing.json: warning: cities[1]: road north of city Rome leads to the city itself [road-to-itself]",
+	}, messages)
+
+	findings = lintDocument(JSONCodec{}, strings.NewReader(`{"cities": `), "testing.json")
+	require.Len(t, findings, 1)
+	require.Equal(t, "error: testing.json: unexpected EOF [syntax]", findings[0].String())
+}
@@ -0,0 +1,155 @@
+package simulator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ivanovpetr/invasion/services/layout"
+)
+
+// Colors of cities and roads in DOT graphs
+const (
+	dotColorDestroyed     = "#cc0000"
+	dotFillDestroyed      = "#f4cccc"
+	dotColorInvaded       = "#1155cc"
+	dotFillInvaded        = "#fff2cc"
+	dotColorDestroyedRoad = "#b7b7b7"
+)
+
+// Size of a grid cell of the layout in DOT graphs in inches
+const (
+	dotCellWidth  = 2
+	dotCellHeight = 1
+)
+
+// dotGraph is a map with the state of cities and aliens rendered in the DOT language of Graphviz
+type dotGraph struct {
+	cities planetMap
+	// cells of cities on the grid derived from directions of roads
+	positions map[string]layout.Point
+	// turn of the battle which destroyed the city by city names
+	destroyedOn map[string]int
+	// identifiers of alive aliens in cities by city names
+	aliens map[string][]int64
+	// identifiers of alive aliens on the road to cities by city names
+	travelling map[string][]int64
+}
+
+// WriteDOT writes the initial map of the simulation as a Graphviz DOT graph, every road is an edge
+// labelled with its direction. Cities are pinned to positions derived from directions of roads,
+// which layout engines like neato respect
+func (s *Simulation) WriteDOT(out io.Writer) error {
+	return dotGraph{cities: s.initialMap, positions: mapLayout(s.initialMap, s.topology).Positions}.write(out)
+}
+
+// WriteDOT writes the resulting map as a Graphviz DOT graph. Destroyed cities are red and annotated
+// with the turn of the battle which destroyed them, cities with surviving aliens list their identifiers
+// and roads to ruins are grey
+func (sr *SimulationResult) WriteDOT(out io.Writer) error {
+	g := dotGraph{
+		cities:      sr.ResultMap,
+		positions:   mapLayout(sr.ResultMap, sr.topology).Positions,
+		destroyedOn: map[string]int{},
+		aliens:      map[string][]int64{},
+		travelling:  map[string][]int64{},
+	}
+	for _, e := range sr.Events {
+		if e, ok := e.(CityDestroyed); ok {
+			g.destroyedOn[e.City] = e.Turn
+		}
+	}
+	for id, a := range sr.Aliens {
+		switch {
+		case a.isDead:
+			continue
+		case a.transit > 0:
+			g.travelling[a.city] = append(g.travelling[a.city], int64(id))
+		default:
+			g.aliens[a.city] = append(g.aliens[a.city], int64(id))
+		}
+	}
+	return g.write(out)
+}
+
+// write writes the graph, cities go in alphabetical order, so the same map always gives the same graph
+func (g dotGraph) write(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintln(w, "digraph invasion {")
+	fmt.Fprintln(w, `	node [shape=box, style="rounded,filled", fillcolor=white, fontname=Helvetica];`)
+	fmt.Fprintln(w, "	edge [fontname=Helvetica, fontsize=10];")
+	names := g.cities.sortedNames()
+	for _, name := range names {
+		fmt.Fprintf(w, "	%s [%s];\n", dotString(name), strings.Join(g.nodeAttributes(g.cities[name]), ", "))
+	}
+	for _, name := range names {
+		for _, d := range g.cities[name].directions {
+			fmt.Fprintf(w, "	%s -> %s [%s];\n", dotString(name), dotString(d.directionValue), strings.Join(g.edgeAttributes(name, d), ", "))
+		}
+	}
+	fmt.Fprintln(w, "}")
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write DOT graph: %w", err)
+	}
+	return nil
+}
+
+// nodeAttributes returns DOT attributes of the city
+func (g dotGraph) nodeAttributes(c *city) []string {
+	label := []string{c.name}
+	var attributes []string
+	if turn, ok := g.destroyedOn[c.name]; ok {
+		label = append(label, fmt.Sprintf("destroyed on turn %d", turn))
+	}
+	if aliens := g.aliens[c.name]; len(aliens) != 0 {
+		label = append(label, "👾 "+joinIDs(aliens))
+	}
+	if aliens := g.travelling[c.name]; len(aliens) != 0 {
+		label = append(label, "on the way 👾 "+joinIDs(aliens))
+	}
+	attributes = append(attributes, "label="+dotString(label...))
+	if p, ok := g.positions[c.name]; ok {
+		// y of Graphviz grows to the north
+		attributes = append(attributes, fmt.Sprintf(`pos="%d,%d!"`, p.X*dotCellWidth, -p.Y*dotCellHeight))
+	}
+	switch {
+	case c.isDestroyed:
+		attributes = append(attributes, "color="+dotString(dotColorDestroyed), "fillcolor="+dotString(dotFillDestroyed), "fontcolor="+dotString(dotColorDestroyed))
+	case len(g.aliens[c.name]) != 0:
+		attributes = append(attributes, "color="+dotString(dotColorInvaded), "fillcolor="+dotString(dotFillInvaded), "penwidth=2")
+	}
+	return attributes
+}
+
+// edgeAttributes returns DOT attributes of the road which leads out of the city
+func (g dotGraph) edgeAttributes(from string, d mapDirection) []string {
+	label := d.directionType
+	if d.transit > 0 {
+		label += fmt.Sprintf(" (%d turns)", d.road().Turns())
+	}
+	attributes := []string{"label=" + dotString(label)}
+	if g.cities[from].isDestroyed || g.cities[d.directionValue].isDestroyed {
+		attributes = append(attributes, "color="+dotString(dotColorDestroyedRoad), "fontcolor="+dotString(dotColorDestroyedRoad), "style=dashed")
+	}
+	return attributes
+}
+
+// dotString returns a quoted DOT string, lines are joined with the DOT line break
+func dotString(lines ...string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(line)
+	}
+	return `"` + strings.Join(escaped, `\n`) + `"`
+}
+
+// joinIDs returns identifiers of aliens separated by commas
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,61 @@
+package simulator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulationWriteDOT(t *testing.T) {
+	input := `"New York" east=Porto:3
+Porto west="New York"`
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	buf := bytes.Buffer{}
+	require.Nil(t, s.WriteDOT(&buf))
+	require.Equal(t, `digraph invasion {
+	node [shape=box, style="rounded,filled", fillcolor=white, fontname=Helvetica];
+	edge [fontname=Helvetica, fontsize=10];
+	"New York" [label="New York", pos="0,0!"];
+	"Porto" [label="Porto", pos="2,0!"];
+	"New York" -> "Porto" [label="east (3 turns)"];
+	"Porto" -> "New York" [label="west"];
+}
+`, buf.String())
+}
+
+func TestSimulationResultWriteDOT(t *testing.T) {
+	input := `Boston east=Porto
+Porto east=Madrid
+Madrid west=Porto
+Lisbon north=Madrid`
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	s.SetSeed(1)
+	cfg := DefaultSimulationConfig(3)
+	cfg.Spawn = ListSpawn{Cities: []string{"Madrid", "Madrid", "Boston"}}
+	res, err := s.Run(cfg)
+	require.Nil(t, err)
+
+	buf := bytes.Buffer{}
+	require.Nil(t, res.WriteDOT(&buf))
+	require.Equal(t, `digraph invasion {
+	node [shape=box, style="rounded,filled", fillcolor=white, fontname=Helvetica];
+	edge [fontname=Helvetica, fontsize=10];
+	"Boston" [label="Boston", pos="0,0!"];
+	"Lisbon" [label="Lisbon", pos="4,-1!"];
+	"Madrid" [label="Madrid\ndestroyed on turn 0", pos="4,0!", color="#cc0000", fillcolor="#f4cccc", fontcolor="#cc0000"];
+	"Porto" [label="Porto\n👾 2", pos="2,0!", color="#1155cc", fillcolor="#fff2cc", penwidth=2];
+	"Boston" -> "Porto" [label="east"];
+	"Lisbon" -> "Madrid" [label="north", color="#b7b7b7", fontcolor="#b7b7b7", style=dashed];
+	"Madrid" -> "Porto" [label="west", color="#b7b7b7", fontcolor="#b7b7b7", style=dashed];
+	"Porto" -> "Madrid" [label="east", color="#b7b7b7", fontcolor="#b7b7b7", style=dashed];
+}
+`, buf.String())
+}
+
+func TestDOTStringEscapesQuotes(t *testing.T) {
+	require.Equal(t, `"say \"hi\"\nC:\\maps"`, dotString(`say "hi"`, `C:\maps`))
+}
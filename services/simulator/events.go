@@ -0,0 +1,110 @@
+package simulator
+
+// EndReason describes why a simulation is over
+type EndReason string
+
+const (
+	// EndReasonAllDead means that all aliens are dead
+	EndReasonAllDead EndReason = "all-dead"
+	// EndReasonLocked means that every alive alien is trapped and can't move anymore
+	EndReasonLocked EndReason = "locked"
+	// EndReasonMaxTurns means that all turns of the simulation are finished
+	EndReasonMaxTurns EndReason = "max-turns"
+)
+
+// Event is something which happened during a simulation
+type Event interface {
+	// EventName returns the name of the event type
+	EventName() string
+	// EventTurn returns the number of the turn when the event happened
+	EventTurn() int
+}
+
+// SimulationStarted is emitted once before aliens land
+type SimulationStarted struct {
+	Turn   int   `json:"turn"`
+	Aliens int64 `json:"aliens"`
+}
+
+// AlienSpawned is emitted when an alien lands in a city
+type AlienSpawned struct {
+	Turn  int    `json:"turn"`
+	Alien int64  `json:"alien"`
+	City  string `json:"city"`
+}
+
+// AlienMoved is emitted when an alien moves from one city to another
+type AlienMoved struct {
+	Turn      int    `json:"turn"`
+	Alien     int64  `json:"alien"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Direction string `json:"direction"`
+	// Transit is the number of turns the alien spends on the road after this one, 0 for ordinary roads
+	Transit int `json:"transit,omitempty"`
+}
+
+// AlienArrived is emitted when an alien arrives in a city at the end of a long road
+type AlienArrived struct {
+	Turn  int    `json:"turn"`
+	Alien int64  `json:"alien"`
+	City  string `json:"city"`
+}
+
+// BattleOccurred is emitted when aliens meet in a city and fight each other
+type BattleOccurred struct {
+	Turn   int     `json:"turn"`
+	City   string  `json:"city"`
+	Aliens []int64 `json:"aliens"`
+}
+
+// CityDestroyed is emitted when a city is destroyed in a battle
+type CityDestroyed struct {
+	Turn   int     `json:"turn"`
+	City   string  `json:"city"`
+	Aliens []int64 `json:"aliens"`
+}
+
+// AlienTrapped is emitted when an alien can't leave its city anymore because all roads lead to ruins
+type AlienTrapped struct {
+	Turn  int    `json:"turn"`
+	Alien int64  `json:"alien"`
+	City  string `json:"city"`
+}
+
+// SimulationEnded is emitted once when the simulation is over
+type SimulationEnded struct {
+	Turn   int       `json:"turn"`
+	Reason EndReason `json:"reason"`
+}
+
+func (e SimulationStarted) EventName() string { return "SimulationStarted" }
+func (e AlienSpawned) EventName() string      { return "AlienSpawned" }
+func (e AlienMoved) EventName() string        { return "AlienMoved" }
+func (e AlienArrived) EventName() string      { return "AlienArrived" }
+func (e BattleOccurred) EventName() string    { return "BattleOccurred" }
+func (e CityDestroyed) EventName() string     { return "CityDestroyed" }
+func (e AlienTrapped) EventName() string      { return "AlienTrapped" }
+func (e SimulationEnded) EventName() string   { return "SimulationEnded" }
+
+func (e SimulationStarted) EventTurn() int { return e.Turn }
+func (e AlienSpawned) EventTurn() int      { return e.Turn }
+func (e AlienMoved) EventTurn() int        { return e.Turn }
+func (e AlienArrived) EventTurn() int      { return e.Turn }
+func (e BattleOccurred) EventTurn() int    { return e.Turn }
+func (e CityDestroyed) EventTurn() int     { return e.Turn }
+func (e AlienTrapped) EventTurn() int      { return e.Turn }
+func (e SimulationEnded) EventTurn() int   { return e.Turn }
+
+// EventSink receives events of a simulation as soon as they happen
+type EventSink interface {
+	Emit(e Event)
+}
+
+// EventSinkFunc allows using an ordinary function as an EventSink
+type EventSinkFunc func(e Event)
+
+// Emit calls f(e)
+func (f EventSinkFunc) Emit(e Event) {
+	f(e)
+}
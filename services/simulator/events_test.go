@@ -0,0 +1,81 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulationEmitsEvents(t *testing.T) {
+	input := `Boston east=Porto
+Porto east=Madrid
+Madrid west=Porto
+Lisbon north=Madrid`
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	s.SetSeed(1)
+	var received []Event
+	cfg := DefaultSimulationConfig(3)
+	cfg.Spawn = ListSpawn{Cities: []string{"Madrid", "Madrid", "Boston"}}
+	cfg.Sink = EventSinkFunc(func(e Event) { received = append(received, e) })
+	res, err := s.Run(cfg)
+	require.Nil(t, err)
+
+	require.Equal(t, []Event{
+		SimulationStarted{Turn: 0, Aliens: 3},
+		AlienSpawned{Turn: 0, Alien: 0, City: "Madrid"},
+		AlienSpawned{Turn: 0, Alien: 1, City: "Madrid"},
+		AlienSpawned{Turn: 0, Alien: 2, City: "Boston"},
+		BattleOccurred{Turn: 0, City: "Madrid", Aliens: []int64{0, 1}},
+		CityDestroyed{Turn: 0, City: "Madrid", Aliens: []int64{0, 1}},
+		AlienMoved{Turn: 0, Alien: 2, From: "Boston", To: "Porto", Direction: "east"},
+		AlienTrapped{Turn: 1, Alien: 2, City: "Porto"},
+		SimulationEnded{Turn: 1, Reason: EndReasonLocked},
+	}, res.Events)
+	require.Equal(t, res.Events, received)
+	require.Equal(t, 2, res.Turns)
+	require.Equal(t, EndReasonLocked, res.EndReason)
+}
+
+func TestSimulationEndsWhenAllAliensAreDead(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	s.SetSeed(1)
+	cfg := DefaultSimulationConfig(2)
+	cfg.Spawn = ListSpawn{Cities: []string{"Paris"}}
+	res, err := s.Run(cfg)
+	require.Nil(t, err)
+	require.Equal(t, SimulationEnded{Turn: 0, Reason: EndReasonAllDead}, res.Events[len(res.Events)-1])
+}
+
+func TestEventsKnowTheirTurn(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	s.SetSeed(5)
+	cfg := DefaultSimulationConfig(10)
+	cfg.MaxTurns = 30
+	res, err := s.Run(cfg)
+	require.Nil(t, err)
+	last := 0
+	for _, e := range res.Events {
+		require.GreaterOrEqual(t, e.EventTurn(), last, e.EventName())
+		last = e.EventTurn()
+	}
+	require.Equal(t, "SimulationEnded", res.Events[len(res.Events)-1].EventName())
+}
+
+func TestSimulationDiscardsEvents(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	s.SetSeed(1)
+	received := 0
+	cfg := DefaultSimulationConfig(5)
+	cfg.DiscardEvents = true
+	cfg.Sink = EventSinkFunc(func(e Event) { received++ })
+	res, err := s.Run(cfg)
+	require.Nil(t, err)
+	require.Empty(t, res.Events)
+	require.Greater(t, received, 0)
+	require.NotEmpty(t, res.EndReason)
+}
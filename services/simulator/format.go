@@ -0,0 +1,100 @@
+package simulator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FormatMap parses a map and returns it in the canonical format: cities keep their order,
+// directions go in the canonical order of the map topology, north, east, south, west by default, and all tokens are separated by single spaces.
+// Comments are kept, consecutive blank lines are merged into one
+func FormatMap(src io.Reader, filename string) ([]byte, error) {
+	p := newParser(src, filename)
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.format(), nil
+}
+
+// FixMapSymmetry formats a map like FormatMap and adds the missing opposite directions to one-way roads.
+// Roads which can't get a way back are returned as MapErrors and the map is left as it is
+func FixMapSymmetry(src io.Reader, filename string) ([]byte, error) {
+	p := newParser(src, filename)
+	if err := p.parseWithOptions(ParseOptions{InferReverseRoads: true}); err != nil {
+		return nil, err
+	}
+	return p.format(), nil
+}
+
+// format writes parsed input in the canonical format
+func (p *parser) format() []byte {
+	buf := bytes.Buffer{}
+	// blank lines are written only between other lines
+	pendingBlank, written := false, false
+	for _, l := range p.layout {
+		if l.kind == layoutBlank {
+			pendingBlank = written
+			continue
+		}
+		if pendingBlank {
+			buf.WriteByte('\n')
+			pendingBlank = false
+		}
+		switch l.kind {
+		case layoutComment:
+			buf.WriteString(formatComment(l.text))
+		case layoutCity:
+			buf.WriteString(p.formatCity(l.text))
+		case layoutTopology:
+			buf.WriteString(formatDirective(topologyDirective+" "+p.topology.String(), l.comment))
+		case layoutAttribute:
+			buf.WriteString(formatDirective(attributeDeclaration(p.schema, l.text), l.comment))
+		}
+		buf.WriteByte('\n')
+		written = true
+	}
+	return buf.Bytes()
+}
+
+// formatCity returns the city line in the canonical format
+func (p *parser) formatCity(name string) string {
+	pc := p.parsedCities[name]
+	builder := strings.Builder{}
+	builder.WriteString(quoteCityName(name))
+	builder.WriteString(formatAttributes(p.schema, pc.attributes))
+	for _, d := range p.topology.canonicalDirections(pc.getDirections()) {
+		builder.WriteString(" " + d.directionType + "=" + formatRoad(d.directionValue, d.transit))
+	}
+	if pc.comment != "" {
+		builder.WriteString(" " + formatComment(pc.comment))
+	}
+	return builder.String()
+}
+
+// formatDirective returns the directive with its comment in the canonical format
+func formatDirective(directive, comment string) string {
+	if comment != "" {
+		directive += " " + formatComment(comment)
+	}
+	return directive
+}
+
+// attributeDeclaration returns the declaration of the custom attribute
+func attributeDeclaration(schema *attributeSchema, name string) string {
+	return fmt.Sprintf("%s %s %s", attributeDirective, name, schema.types[name])
+}
+
+// formatComment returns the comment text with the comment sign and without trailing spaces
+func formatComment(text string) string {
+	return string(commentSign) + strings.TrimRight(text, " \t\r")
+}
+
+// formatRoad returns the direction value with the weight of the road if the road takes more than one turn
+func formatRoad(city string, transit int) string {
+	if transit == 0 {
+		return quoteCityName(city)
+	}
+	return fmt.Sprintf("%s%c%d", quoteCityName(city), roadWeightSign, transit+1)
+}
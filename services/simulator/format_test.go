@@ -0,0 +1,104 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatMapProducesCanonicalForm(t *testing.T) {
+	input := `
+
+# Europe   
+Paris  west=Madrid   south=Rome north=Berlin	east=Moscow #   the capital of France  
+
+
+   #indented comment
+Berlin south=Paris
+Moscow west=Paris#trailing comment
+Madrid east=Paris
+Rome north=Paris
+
+`
+	expected := `# Europe
+Paris north=Berlin east=Moscow south=Rome west=Madrid #   the capital of France
+
+#indented comment
+Berlin south=Paris
+Moscow west=Paris #trailing comment
+Madrid east=Paris
+Rome north=Paris
+`
+	formatted, err := FormatMap(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	require.Equal(t, expected, string(formatted))
+}
+
+func TestFormatMapIsIdempotent(t *testing.T) {
+	formatted, err := FormatMap(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	again, err := FormatMap(strings.NewReader(string(formatted)), "testing")
+	require.Nil(t, err)
+	require.Equal(t, string(formatted), string(again))
+}
+
+func TestFormatMapKeepsCityOrder(t *testing.T) {
+	input := "Rome north=Paris\nParis south=Rome\r\n"
+	formatted, err := FormatMap(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	require.Equal(t, "Rome north=Paris\nParis south=Rome\n", string(formatted))
+}
+
+func TestFormatMapFailsOnInvalidMap(t *testing.T) {
+	_, err := FormatMap(strings.NewReader("Paris up=Rome"), "testing")
+	require.EqualError(t, err, "testing:1:9: got unexpected mapDirection type up, expected one of south,north,west,east")
+}
+
+func TestFixMapSymmetryAddsMissingRoads(t *testing.T) {
+	input := "# one-way roads\nParis south=Boston west=Madrid\nBoston east=Rome #port\nRome west=Boston\nMadrid north=Rome\n"
+	expected := "# one-way roads\nParis south=Boston west=Madrid\nBoston north=Paris east=Rome #port\nRome south=Madrid west=Boston\nMadrid north=Rome east=Paris\n"
+	fixed, err := FixMapSymmetry(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	require.Equal(t, expected, string(fixed))
+}
+
+func TestFixMapSymmetryFailsOnConflicts(t *testing.T) {
+	_, err := FixMapSymmetry(strings.NewReader("Paris south=Boston\nRome south=Boston\nBoston north=Rome\n"), "testing")
+	require.EqualError(t, err, "testing:1:19: cannot add road back from Boston to Paris, direction north of Boston already leads to Rome")
+}
+
+func TestFormatMapKeepsTopologyDeclaration(t *testing.T) {
+	input := "# octagonal map\n@topology   octagonal   #eight neighbours\nParis west=Madrid northeast=Berlin\nBerlin southwest=Paris\nMadrid east=Paris\n"
+	expected := "# octagonal map\n@topology octagonal #eight neighbours\nParis northeast=Berlin west=Madrid\nBerlin southwest=Paris\nMadrid east=Paris\n"
+	formatted, err := FormatMap(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	require.Equal(t, expected, string(formatted))
+
+	formatted, err = FormatMap(strings.NewReader("@topology left:right   up:down\nA up=B\nB down=A\n"), "testing")
+	require.Nil(t, err)
+	require.Equal(t, "@topology left:right up:down\nA up=B\nB down=A\n", string(formatted))
+}
+
+func TestFormatMapQuotesOnlyNamesWithSpaces(t *testing.T) {
+	input := "\"São_Paulo\" north=\"New York\"\n\"New York\" south=São_Paulo\n"
+	formatted, err := FormatMap(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	require.Equal(t, "São_Paulo north=\"New York\"\n\"New York\" south=São_Paulo\n", string(formatted))
+}
+
+func TestFormatMapKeepsAttributes(t *testing.T) {
+	input := "@attribute country   string #ISO name\nParis north=Berlin  country=\"French Republic\" defense=3 population=2100000\nBerlin south=Paris\n"
+	expected := "@attribute country string #ISO name\nParis population=2100000 defense=3 country=\"French Republic\" north=Berlin\nBerlin south=Paris\n"
+	formatted, err := FormatMap(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	require.Equal(t, expected, string(formatted))
+}
+
+func TestFormatMapKeepsRoadWeights(t *testing.T) {
+	input := "Boston east=Vladivostok:4 north=\"New York\":1\nVladivostok west=Boston:4\n\"New York\" south=Boston\n"
+	expected := "Boston north=\"New York\" east=Vladivostok:4\nVladivostok west=Boston:4\n\"New York\" south=Boston\n"
+	formatted, err := FormatMap(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	require.Equal(t, expected, string(formatted))
+}
@@ -0,0 +1,531 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/ivanovpetr/invasion/services/layout"
+)
+
+// Shapes of generated maps
+const (
+	// ShapeGrid places cities on a grid and connects neighbour cells, chains and dead ends take free cells next to
+	// the cities they are attached to, so directions of roads are geometrically consistent
+	ShapeGrid = "grid"
+	// ShapeRandom connects cities with random roads
+	ShapeRandom = "random"
+	// ShapeRing connects cities into a circle, optional roads are shortcuts over one city
+	ShapeRing = "ring"
+	// ShapeScaleFree connects every new city to the existing ones which already have many roads, so few hubs appear
+	ShapeScaleFree = "scale-free"
+)
+
+// nameSyllables are joined into names of generated cities
+var nameSyllables = []string{
+	"ba", "bel", "do", "dra", "ka", "kor", "la", "len", "mi", "mor", "na", "nor",
+	"po", "ra", "rin", "sa", "sol", "ta", "tor", "va", "ven", "za", "ul", "os",
+}
+
+// GenerateOptions describes a map to generate
+type GenerateOptions struct {
+	// Cities is the number of cities of the map besides cities of chains and dead ends
+	Cities int
+	// Shape is one of ShapeGrid, ShapeRandom, ShapeRing and ShapeScaleFree
+	Shape string
+	// Density is the probability to build every optional road, roads which keep parts of the map connected are always built
+	Density float64
+	// Seed of the random generator, the same options and seed always give the same map
+	Seed int64
+	// Symmetric makes every road two-way, otherwise every road is one-way with probability 1/2
+	Symmetric bool
+	// Topology of the map, the default topology if it is nil. Only directions which have opposite ones are used
+	Topology *Topology
+	// Islands is the number of parts of the map which are not connected with each other, 1 if it is zero
+	Islands int
+	// Chains is the number of long chains of cities attached to random cities
+	Chains int
+	// ChainLength is the number of cities of every chain
+	ChainLength int
+	// DeadEnds is the number of cities with a single road attached to random cities
+	DeadEnds int
+}
+
+// generatedCity is a city of a map being generated
+type generatedCity struct {
+	name string
+	// indexes of neighbour cities by directions of roads
+	roads map[string]int
+}
+
+// generator builds a map from the random generator
+type generator struct {
+	rnd      *rand.Rand
+	topology *Topology
+	// directions which have opposite ones
+	directions []string
+	cities     []*generatedCity
+	names      map[string]bool
+	// roads which may become one-way, every road is kept once
+	roads [][2]int
+	// cells of the grid taken by cities and positions of cities, both are nil unless the map is a grid
+	cells     map[layout.Point]int
+	positions map[int]layout.Point
+}
+
+// GenerateMap generates a random map which is valid for simulations
+func GenerateMap(opts GenerateOptions) (*MapDocument, error) {
+	if opts.Topology == nil {
+		opts.Topology = DefaultTopology()
+	}
+	if opts.Islands == 0 {
+		opts.Islands = 1
+	}
+	switch {
+	case opts.Cities < 2:
+		return nil, fmt.Errorf("number of cities must be at least 2, got %d", opts.Cities)
+	case opts.Density < 0 || opts.Density > 1:
+		return nil, fmt.Errorf("density must be between 0 and 1, got %g", opts.Density)
+	case opts.Islands < 0 || 2*opts.Islands > opts.Cities:
+		// every island needs at least two cities, a city without roads is not valid
+		return nil, fmt.Errorf("number of islands must be between 1 and half of the number of cities %d, got %d", opts.Cities, opts.Islands)
+	case opts.Chains < 0:
+		return nil, fmt.Errorf("number of chains must not be negative, got %d", opts.Chains)
+	case opts.Chains > 0 && opts.ChainLength <= 0:
+		return nil, fmt.Errorf("length of chains must be positive, got %d", opts.ChainLength)
+	case opts.DeadEnds < 0:
+		return nil, fmt.Errorf("number of dead ends must not be negative, got %d", opts.DeadEnds)
+	}
+
+	g := &generator{rnd: rand.New(rand.NewSource(opts.Seed)), topology: opts.Topology, names: map[string]bool{}}
+	for _, d := range opts.Topology.directions {
+		if opts.Topology.Opposite(d) != "" {
+			g.directions = append(g.directions, d)
+		}
+	}
+	if len(g.directions) == 0 {
+		return nil, fmt.Errorf("topology %s has no opposite directions to build roads", opts.Topology)
+	}
+	if opts.Shape == ShapeGrid {
+		g.cells, g.positions = map[layout.Point]int{}, map[int]layout.Point{}
+	}
+
+	// cities are shared between islands as evenly as possible
+	for i := 0; i < opts.Islands; i++ {
+		size := opts.Cities / opts.Islands
+		if i < opts.Cities%opts.Islands {
+			size++
+		}
+		first := len(g.cities)
+		var err error
+		switch opts.Shape {
+		case ShapeGrid:
+			err = g.grid(size, opts.Density)
+		case ShapeRandom:
+			g.random(size, opts.Density)
+		case ShapeRing:
+			g.ring(size, opts.Density)
+		case ShapeScaleFree:
+			g.scaleFree(size, opts.Density)
+		default:
+			return nil, fmt.Errorf("unknown shape %s, expected one of grid,random,ring,scale-free", opts.Shape)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := g.connectIsolated(first, len(g.cities)); err != nil {
+			return nil, err
+		}
+	}
+	// dead ends are attached to cities which existed before, so they never grow from each other
+	anchors := len(g.cities)
+	for i := 0; i < opts.Chains; i++ {
+		g.chain(opts.ChainLength)
+	}
+	for i := 0; i < opts.DeadEnds; i++ {
+		g.deadEnd(anchors)
+	}
+	if err := g.connectIsolated(0, len(g.cities)); err != nil {
+		return nil, err
+	}
+	if !opts.Symmetric {
+		g.dropWaysBack()
+	}
+	return g.document(), nil
+}
+
+// addCity adds a city with a new random name and returns its index
+func (g *generator) addCity() int {
+	name := ""
+	for attempt := 0; name == "" || g.names[name]; attempt++ {
+		syllables := 2 + g.rnd.Intn(2)
+		builder := strings.Builder{}
+		for i := 0; i < syllables; i++ {
+			builder.WriteString(nameSyllables[g.rnd.Intn(len(nameSyllables))])
+		}
+		name = strings.Title(builder.String())
+		// names run out on huge maps, numbers keep them unique
+		if attempt > 10 {
+			name += "_" + strconv.Itoa(len(g.cities))
+		}
+	}
+	g.names[name] = true
+	g.cities = append(g.cities, &generatedCity{name: name, roads: map[string]int{}})
+	return len(g.cities) - 1
+}
+
+// connect builds a two-way road from city a to city b in the direction. On a grid a city which
+// has no cell yet takes the cell the road leads to
+func (g *generator) connect(a, b int, direction string) {
+	if g.cells != nil {
+		offset, _ := directionOffset(g.topology, direction)
+		pa, placedA := g.positions[a]
+		pb, placedB := g.positions[b]
+		switch {
+		case placedA && !placedB:
+			g.place(b, pa.Add(offset))
+		case placedB && !placedA:
+			g.place(a, pb.Sub(offset))
+		}
+	}
+	g.cities[a].roads[direction] = b
+	g.cities[b].roads[g.topology.Opposite(direction)] = a
+	g.roads = append(g.roads, [2]int{a, b})
+}
+
+// place puts the city on the cell of the grid
+func (g *generator) place(city int, p layout.Point) {
+	g.cells[p] = city
+	g.positions[city] = p
+}
+
+// fitsGrid checks whether a road from city a to city b in the direction agrees with cells of the grid.
+// A city which is not on the grid yet may take only a free cell, a road between two such cities doesn't fit
+func (g *generator) fitsGrid(a, b int, direction string) bool {
+	offset, ok := directionOffset(g.topology, direction)
+	if !ok {
+		return false
+	}
+	pa, placedA := g.positions[a]
+	pb, placedB := g.positions[b]
+	switch {
+	case placedA && placedB:
+		return pa.Add(offset) == pb
+	case placedA:
+		_, taken := g.cells[pa.Add(offset)]
+		return !taken
+	case placedB:
+		_, taken := g.cells[pb.Sub(offset)]
+		return !taken
+	default:
+		return false
+	}
+}
+
+// freeDirections returns directions which are free in city a with opposite directions free in city b.
+// On a grid only directions which agree with cells of the grid are free
+func (g *generator) freeDirections(a, b int) []string {
+	var free []string
+	for _, d := range g.directions {
+		if _, ok := g.cities[a].roads[d]; ok {
+			continue
+		}
+		if _, ok := g.cities[b].roads[g.topology.Opposite(d)]; ok {
+			continue
+		}
+		if g.cells != nil && !g.fitsGrid(a, b, d) {
+			continue
+		}
+		free = append(free, d)
+	}
+	return free
+}
+
+// link builds a two-way road between cities in a random free direction. False is returned when the cities
+// are already connected or all directions are taken
+func (g *generator) link(a, b int) bool {
+	if a == b || g.connected(a, b) {
+		return false
+	}
+	free := g.freeDirections(a, b)
+	if len(free) == 0 {
+		return false
+	}
+	g.connect(a, b, free[g.rnd.Intn(len(free))])
+	return true
+}
+
+// attach links the new city to a random city from the range which has a free direction
+func (g *generator) attach(city, from, to int) bool {
+	candidates := make([]int, 0, to-from)
+	for i := from; i < to; i++ {
+		if i != city && !g.connected(i, city) && len(g.freeDirections(i, city)) != 0 {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return false
+	}
+	return g.link(candidates[g.rnd.Intn(len(candidates))], city)
+}
+
+// connectIsolated attaches cities of the range which have no roads to other cities of the range,
+// because a city without roads is not valid
+func (g *generator) connectIsolated(from, to int) error {
+	for i := from; i < to; i++ {
+		if len(g.cities[i].roads) == 0 && !g.attach(i, from, to) {
+			return fmt.Errorf("failed to build a road to city %s, all directions are taken", g.cities[i].name)
+		}
+	}
+	return nil
+}
+
+// grid places cities on cells of a grid row by row and connects neighbour cells. Rows of the hex topology
+// are shifted by half of a cell. Every island takes its own cells to the right of the previous ones.
+// A random spanning tree of the grid keeps it connected
+func (g *generator) grid(size int, density float64) error {
+	offsets := map[string]layout.Point{}
+	for _, d := range g.directions {
+		if offset, ok := directionOffset(g.topology, d); ok {
+			offsets[d] = offset
+		}
+	}
+	if len(offsets) == 0 {
+		return fmt.Errorf("topology %s has no directions with a geometric meaning to build a grid", g.topology)
+	}
+
+	columns := int(math.Ceil(math.Sqrt(float64(size))))
+	first := len(g.cities)
+	// the left column is even, so cells of hexagons keep their parity
+	left := 0
+	for _, p := range g.positions {
+		if p.X+2 > left {
+			left = p.X + 2 + p.X%2
+		}
+	}
+	cell := func(i int) layout.Point {
+		p := layout.Point{X: i % columns, Y: i / columns}
+		if g.topology.name == TopologyHex {
+			p.X = 2*p.X + p.Y%2
+		}
+		p.X += left
+		return p
+	}
+	for i := 0; i < size; i++ {
+		g.place(g.addCity(), cell(i))
+	}
+
+	type gridRoad struct {
+		from, to  int
+		direction string
+	}
+	var roads []gridRoad
+	for i := first; i < len(g.cities); i++ {
+		p := cell(i - first)
+		// every pair of neighbours is found once from the city which comes first
+		for _, d := range g.directions {
+			offset, ok := offsets[d]
+			if !ok {
+				continue
+			}
+			if j, ok := g.cells[p.Add(offset)]; ok && j > i {
+				roads = append(roads, gridRoad{from: i, to: j, direction: d})
+			}
+		}
+	}
+	g.rnd.Shuffle(len(roads), func(i, j int) { roads[i], roads[j] = roads[j], roads[i] })
+
+	parents := map[int]int{}
+	var root func(int) int
+	root = func(c int) int {
+		if p, ok := parents[c]; ok && p != c {
+			parents[c] = root(p)
+			return parents[c]
+		}
+		return c
+	}
+	var optional []gridRoad
+	for _, r := range roads {
+		if a, b := root(r.from), root(r.to); a != b {
+			parents[a] = b
+			g.connect(r.from, r.to, r.direction)
+			continue
+		}
+		optional = append(optional, r)
+	}
+	for _, r := range optional {
+		if g.rnd.Float64() < density {
+			g.connect(r.from, r.to, r.direction)
+		}
+	}
+	return nil
+}
+
+// random connects every city to a random city added before it, optional roads connect random pairs of cities
+func (g *generator) random(size int, density float64) {
+	first := len(g.cities)
+	for i := 0; i < size; i++ {
+		city := g.addCity()
+		if city != first {
+			g.attach(city, first, city)
+		}
+	}
+	for i := 0; i < size; i++ {
+		a, b := first+g.rnd.Intn(size), first+g.rnd.Intn(size)
+		if g.rnd.Float64() < density {
+			g.link(a, b)
+		}
+	}
+}
+
+// ring connects cities into a circle, optional roads are shortcuts between cities two steps away from each other
+func (g *generator) ring(size int, density float64) {
+	first := len(g.cities)
+	for i := 0; i < size; i++ {
+		g.addCity()
+	}
+	for i := 0; i < size; i++ {
+		g.link(first+i, first+(i+1)%size)
+	}
+	for i := 0; i < size; i++ {
+		if g.rnd.Float64() < density {
+			g.link(first+i, first+(i+2)%size)
+		}
+	}
+}
+
+// scaleFree connects every new city to a city chosen proportionally to the number of its roads,
+// an optional road connects it to one more city chosen the same way
+func (g *generator) scaleFree(size int, density float64) {
+	first := len(g.cities)
+	for i := 0; i < size; i++ {
+		city := g.addCity()
+		if city == first {
+			continue
+		}
+		g.linkPreferentially(city, first)
+		if g.rnd.Float64() < density {
+			g.linkPreferentially(city, first)
+		}
+	}
+}
+
+// linkPreferentially links the city to one of the cities added before it starting from the first one,
+// a city with n roads is chosen with the weight n+1
+func (g *generator) linkPreferentially(city, first int) {
+	total := 0
+	weights := make([]int, city-first)
+	for i := range weights {
+		c := first + i
+		if len(g.freeDirections(c, city)) != 0 && !g.connected(c, city) {
+			weights[i] = len(g.cities[c].roads) + 1
+			total += weights[i]
+		}
+	}
+	if total == 0 {
+		return
+	}
+	pick := g.rnd.Intn(total)
+	for i, w := range weights {
+		if pick < w {
+			g.link(first+i, city)
+			return
+		}
+		pick -= w
+	}
+}
+
+// connected checks whether there is a road between cities
+func (g *generator) connected(a, b int) bool {
+	for _, c := range g.cities[a].roads {
+		if c == b {
+			return true
+		}
+	}
+	return false
+}
+
+// chain attaches a straight chain of new cities to a random city, every city of the chain
+// continues it in the same direction while it can
+func (g *generator) chain(length int) {
+	previous := g.addCity()
+	if previous != 0 {
+		g.attach(previous, 0, previous)
+	}
+	direction := ""
+	for i := 1; i < length; i++ {
+		city := g.addCity()
+		free := g.freeDirections(previous, city)
+		if len(free) == 0 {
+			// the chain ran into other cities, so it goes on from a random city
+			g.attach(city, 0, city)
+			previous = city
+			continue
+		}
+		if !containsString(free, direction) {
+			direction = free[g.rnd.Intn(len(free))]
+		}
+		g.connect(previous, city, direction)
+		previous = city
+	}
+}
+
+// deadEnd attaches a new city with a single road to one of the first cities
+func (g *generator) deadEnd(anchors int) {
+	city := g.addCity()
+	if anchors > 0 {
+		g.attach(city, 0, anchors)
+	}
+}
+
+// dropWaysBack makes every road one-way with probability 1/2, the direction of the road is random.
+// The last road of a city is never dropped
+func (g *generator) dropWaysBack() {
+	for _, r := range g.roads {
+		if g.rnd.Intn(2) == 0 {
+			continue
+		}
+		a, b := r[0], r[1]
+		if g.rnd.Intn(2) == 0 {
+			a, b = b, a
+		}
+		if len(g.cities[a].roads) == 1 {
+			continue
+		}
+		for d, c := range g.cities[a].roads {
+			if c == b {
+				delete(g.cities[a].roads, d)
+			}
+		}
+	}
+}
+
+// document returns the generated map, roads of every city go in the order of the topology
+func (g *generator) document() *MapDocument {
+	doc := &MapDocument{Cities: make([]CityDocument, 0, len(g.cities))}
+	if g.topology.name != TopologyCompass {
+		doc.Topology = g.topology.String()
+	}
+	for _, c := range g.cities {
+		city := CityDocument{Name: c.name, Roads: []RoadDocument{}}
+		for _, d := range g.topology.order {
+			if neighbour, ok := c.roads[d]; ok {
+				city.Roads = append(city.Roads, RoadDocument{Direction: d, City: g.cities[neighbour].name})
+			}
+		}
+		doc.Cities = append(doc.Cities, city)
+	}
+	return doc
+}
+
+// containsString checks whether the slice contains the string
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
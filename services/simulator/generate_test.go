@@ -0,0 +1,121 @@
+package simulator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// generateAndLint generates a map, checks that it is valid and returns its findings
+func generateAndLint(t *testing.T, opts GenerateOptions) (*MapDocument, []Finding) {
+	doc, err := GenerateMap(opts)
+	require.Nil(t, err)
+	_, err = CreateSimulationFromDocument(doc, ParseOptions{})
+	require.Nil(t, err)
+	buf := bytes.Buffer{}
+	require.Nil(t, EmapCodec{}.Encode(&buf, doc))
+	return doc, lintMap(&buf, "generated")
+}
+
+// findingRules counts findings by rules
+func findingRules(findings []Finding) map[string]int {
+	rules := map[string]int{}
+	for _, f := range findings {
+		rules[f.Rule]++
+	}
+	return rules
+}
+
+func TestGenerateMapShapes(t *testing.T) {
+	for _, shape := range []string{ShapeGrid, ShapeRandom, ShapeRing, ShapeScaleFree} {
+		for seed := int64(0); seed < 10; seed++ {
+			doc, findings := generateAndLint(t, GenerateOptions{Cities: 30, Shape: shape, Density: 0.7, Seed: seed, Symmetric: true})
+			require.Len(t, doc.Cities, 30, shape)
+			rules := findingRules(findings)
+			require.Zero(t, rules[RuleAsymmetricRoad], shape)
+			require.Zero(t, rules[RuleDisconnectedMap], shape)
+			require.Zero(t, rules[RuleIsolatedCity], shape)
+		}
+	}
+}
+
+func TestGenerateMapGridIsGeometricallyConsistent(t *testing.T) {
+	for _, topology := range []*Topology{compassTopology, octagonalTopology, hexTopology} {
+		doc, findings := generateAndLint(t, GenerateOptions{Cities: 25, Shape: ShapeGrid, Density: 0.5, Seed: 7, Symmetric: true, Topology: topology})
+		require.Empty(t, findings, topology.name)
+		if topology != compassTopology {
+			require.Equal(t, topology.name, doc.Topology)
+		}
+	}
+}
+
+func TestGenerateMapGridWithChainsAndDeadEndsIsGeometricallyConsistent(t *testing.T) {
+	for _, topology := range []*Topology{compassTopology, octagonalTopology, hexTopology} {
+		for seed := int64(0); seed < 20; seed++ {
+			doc, findings := generateAndLint(t, GenerateOptions{
+				Cities: 20, Shape: ShapeGrid, Density: 0.5, Seed: seed, Symmetric: true, Topology: topology,
+				Islands: 2, Chains: 3, ChainLength: 6, DeadEnds: 8,
+			})
+			require.Len(t, doc.Cities, 20+3*6+8)
+			require.Zero(t, findingRules(findings)[RuleInconsistentGeometry], "%s seed %d", topology.name, seed)
+		}
+	}
+}
+
+func TestGenerateMapIsReproducible(t *testing.T) {
+	opts := GenerateOptions{Cities: 20, Shape: ShapeScaleFree, Density: 0.7, Seed: 42, Chains: 2, ChainLength: 4, DeadEnds: 3}
+	first, err := GenerateMap(opts)
+	require.Nil(t, err)
+	second, err := GenerateMap(opts)
+	require.Nil(t, err)
+	require.Equal(t, first, second)
+
+	opts.Seed = 43
+	third, err := GenerateMap(opts)
+	require.Nil(t, err)
+	require.NotEqual(t, first, third)
+}
+
+func TestGenerateMapIslandsChainsAndDeadEnds(t *testing.T) {
+	doc, findings := generateAndLint(t, GenerateOptions{
+		Cities: 12, Shape: ShapeRing, Seed: 3, Symmetric: true,
+		Islands: 3, Chains: 2, ChainLength: 5, DeadEnds: 4,
+	})
+	require.Len(t, doc.Cities, 12+2*5+4)
+	require.Equal(t, 2, findingRules(findings)[RuleDisconnectedMap])
+
+	// the last cities are dead ends with a single road
+	for _, c := range doc.Cities[len(doc.Cities)-4:] {
+		require.Len(t, c.Roads, 1)
+	}
+	// every city of a chain but the last one leads to the next city in the same direction
+	chain := doc.Cities[12:17]
+	for i := 1; i < len(chain)-1; i++ {
+		require.Len(t, chain[i].Roads, 2)
+	}
+}
+
+func TestGenerateMapWithOneWayRoads(t *testing.T) {
+	doc, findings := generateAndLint(t, GenerateOptions{Cities: 30, Shape: ShapeGrid, Density: 1, Seed: 1})
+	require.NotZero(t, findingRules(findings)[RuleAsymmetricRoad])
+	for _, c := range doc.Cities {
+		require.NotEmpty(t, c.Roads)
+	}
+}
+
+func TestGenerateMapValidatesOptions(t *testing.T) {
+	for _, tc := range []struct {
+		opts GenerateOptions
+		err  string
+	}{
+		{GenerateOptions{Cities: 1, Shape: ShapeGrid}, "number of cities must be at least 2, got 1"},
+		{GenerateOptions{Cities: 10, Shape: ShapeGrid, Density: 1.5}, "density must be between 0 and 1, got 1.5"},
+		{GenerateOptions{Cities: 10, Shape: ShapeGrid, Islands: 6}, "number of islands must be between 1 and half of the number of cities 10, got 6"},
+		{GenerateOptions{Cities: 10, Shape: ShapeGrid, Chains: 1}, "length of chains must be positive, got 0"},
+		{GenerateOptions{Cities: 10, Shape: "tree"}, "unknown shape tree, expected one of grid,random,ring,scale-free"},
+	} {
+		_, err := GenerateMap(tc.opts)
+		require.EqualError(t, err, tc.err)
+	}
+}
@@ -0,0 +1,73 @@
+package simulator
+
+import "github.com/ivanovpetr/invasion/services/layout"
+
+// directionOffsets are offsets of neighbours in directions which have a geometric meaning
+var directionOffsets = map[string]layout.Point{
+	directionNorth: {X: 0, Y: -1},
+	directionSouth: {X: 0, Y: 1},
+	directionEast:  {X: 1, Y: 0},
+	directionWest:  {X: -1, Y: 0},
+	"northeast":    {X: 1, Y: -1},
+	"northwest":    {X: -1, Y: -1},
+	"southeast":    {X: 1, Y: 1},
+	"southwest":    {X: -1, Y: 1},
+	"up":           {X: 0, Y: -1},
+	"down":         {X: 0, Y: 1},
+	"left":         {X: -1, Y: 0},
+	"right":        {X: 1, Y: 0},
+}
+
+// directionAliases are directions which mean the same as compass directions
+var directionAliases = map[string]string{
+	"up":    directionNorth,
+	"down":  directionSouth,
+	"left":  directionWest,
+	"right": directionEast,
+}
+
+// directionOffset returns the offset of the neighbour in the direction. Hexagons of the hex topology
+// use doubled coordinates, so their east and west neighbours are two cells away. An alias of a compass
+// direction has no geometric meaning in a topology which has the compass direction itself, for example
+// up and north of a topology of floors and streets
+func directionOffset(topology *Topology, direction string) (layout.Point, bool) {
+	if compass, ok := directionAliases[direction]; ok && topology.Has(compass) {
+		return layout.Point{}, false
+	}
+	offset, ok := directionOffsets[direction]
+	if topology.name == TopologyHex && (direction == directionEast || direction == directionWest) {
+		offset.X *= 2
+	}
+	return offset, ok
+}
+
+// layoutLinks returns roads of cities as links of the layout
+func layoutLinks(topology *Topology, names []string, directions func(name string) []mapDirection) []layout.Link {
+	if topology == nil {
+		topology = DefaultTopology()
+	}
+	var links []layout.Link
+	for _, name := range names {
+		for _, d := range directions(name) {
+			offset, ok := directionOffset(topology, d.directionType)
+			links = append(links, layout.Link{From: name, To: d.directionValue, Direction: d.directionType, Offset: offset, Geometric: ok})
+		}
+	}
+	return links
+}
+
+// mapLayout places every city of the map on a grid cell following directions of roads,
+// so the north neighbour of a city is one cell above it
+func mapLayout(cities planetMap, topology *Topology) *layout.Layout {
+	names := cities.sortedNames()
+	return layout.Compute(names, layoutLinks(topology, names, func(name string) []mapDirection {
+		return cities[name].directions
+	}))
+}
+
+// Layout places cities of the initial map on a grid following directions of roads. Besides coordinates
+// of cities the layout has roads which contradict each other, for example a city which is north of another one
+// and also south of it via other roads
+func (s *Simulation) Layout() *layout.Layout {
+	return mapLayout(s.initialMap, s.topology)
+}
@@ -0,0 +1,82 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ivanovpetr/invasion/services/layout"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapLayoutFollowsDirections(t *testing.T) {
+	input := `Paris north=Berlin east=Rome south=Madrid west=Lisbon
+Berlin south=Paris
+Rome west=Paris
+Madrid north=Paris
+Lisbon east=Paris`
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	require.Equal(t, map[string]layout.Point{
+		"Paris":  {X: 1, Y: 1},
+		"Berlin": {X: 1, Y: 0},
+		"Rome":   {X: 2, Y: 1},
+		"Madrid": {X: 1, Y: 2},
+		"Lisbon": {X: 0, Y: 1},
+	}, s.Layout().Positions)
+}
+
+func TestMapLayoutResolvesTakenCells(t *testing.T) {
+	// both Berlin and Rome want to be to the north of Paris
+	input := `Paris north=Berlin
+Rome south=Paris
+Berlin south=Paris`
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	positions := s.Layout().Positions
+	require.Len(t, positions, 3)
+	require.NotEqual(t, positions["Berlin"], positions["Rome"])
+	require.Equal(t, positions["Paris"].Add(layout.Point{Y: -1}), positions["Berlin"])
+}
+
+func TestMapLayoutPlacesDisconnectedPartsSideBySide(t *testing.T) {
+	input := `@topology hex
+A east=B
+B west=A
+C northeast=D
+D southwest=C`
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	require.Equal(t, map[string]layout.Point{
+		"A": {X: 0, Y: 0},
+		"B": {X: 2, Y: 0},
+		"C": {X: 4, Y: 1},
+		"D": {X: 5, Y: 0},
+	}, s.Layout().Positions)
+}
+
+func TestMapLayoutFindsContradictions(t *testing.T) {
+	// Oslo is north of Paris and also south of it via Rome
+	input := `Paris north=Oslo east=Rome
+Rome south=Oslo
+Oslo south=Paris`
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	l := s.Layout()
+	require.Len(t, l.Conflicts, 1)
+	require.Equal(t, "Paris", l.Conflicts[0].Link.From)
+	require.Equal(t, "east", l.Conflicts[0].Link.Direction)
+	require.Empty(t, l.Overlaps)
+}
+
+func TestDirectionOffsetOfAliases(t *testing.T) {
+	floors, err := ParseTopology("up:down north:south")
+	require.Nil(t, err)
+	_, ok := directionOffset(floors, "up")
+	require.False(t, ok)
+
+	screen, err := ParseTopology("up:down left:right")
+	require.Nil(t, err)
+	offset, ok := directionOffset(screen, "up")
+	require.True(t, ok)
+	require.Equal(t, layout.Point{Y: -1}, offset)
+}
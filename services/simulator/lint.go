@@ -0,0 +1,391 @@
+package simulator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	"github.com/ivanovpetr/invasion/services/layout"
+)
+
+// Severity shows how serious a problem found in a map is
+type Severity string
+
+const (
+	// SeverityError means that the map can't be used for simulations
+	SeverityError Severity = "error"
+	// SeverityWarning means that the map can be used, but it is most likely a mistake
+	SeverityWarning Severity = "warning"
+	// SeverityInfo means that the map has a notable feature which may be intended
+	SeverityInfo Severity = "info"
+)
+
+// Lint rules which produce findings
+const (
+	RuleSyntax               = "syntax"
+	RuleDanglingRoad         = "dangling-road"
+	RuleAsymmetricRoad       = "asymmetric-road"
+	RuleRoadToItself         = "road-to-itself"
+	RuleIsolatedCity         = "isolated-city"
+	RuleUnreachableCity      = "unreachable-city"
+	RuleDisconnectedMap      = "disconnected-map"
+	RuleClosedNeighbourhood  = "closed-neighbourhood"
+	RuleInconsistentGeometry = "inconsistent-geometry"
+)
+
+// Finding is a problem found in a map by the parser or a lint rule
+type Finding struct {
+	Severity Severity
+	Rule     string
+	Position scanner.Position
+	Message  string
+}
+
+func (f Finding) String() string {
+	if !f.Position.IsValid() {
+		if f.Position.Filename == "" {
+			return fmt.Sprintf("%s: %s [%s]", f.Severity, f.Message, f.Rule)
+		}
+		return fmt.Sprintf("%s: %s: %s [%s]", f.Position.Filename, f.Severity, f.Message, f.Rule)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s [%s]", f.Position.Filename, f.Position.Line, f.Position.Column, f.Severity, f.Message, f.Rule)
+}
+
+// LintMapFromPath parses a map file and checks it with all lint rules. Returned error means
+// that the file can't be read, problems of the map itself are returned as findings
+func LintMapFromPath(path string) ([]Finding, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	filename := filepath.Base(path)
+	if codec, ok := DetectMapCodec(filename, src).(documentCodec); ok {
+		return lintDocument(codec, bytes.NewReader(src), filename), nil
+	}
+	return lintMap(bytes.NewReader(src), filename), nil
+}
+
+// lintDocument checks a map document with all lint rules, findings have paths in the document instead of positions
+func lintDocument(codec documentCodec, src io.Reader, filename string) []Finding {
+	doc, err := codec.unmarshal(src, filename)
+	if err != nil {
+		return errorFindings(RuleSyntax, err)
+	}
+	text, paths, err := doc.render(filename)
+	if err != nil {
+		return errorFindings(RuleSyntax, err)
+	}
+	findings := lintMap(bytes.NewReader(text), filename)
+	for i, f := range findings {
+		if path, ok := documentPath(paths, f.Position); ok {
+			findings[i].Position = scanner.Position{Filename: filename}
+			findings[i].Message = path + ": " + f.Message
+		}
+	}
+	return findings
+}
+
+// lintMap parses input and checks it with all lint rules. Lint rules run only when the input
+// has no errors, because a partially parsed map gives misleading findings
+func lintMap(src io.Reader, filename string) []Finding {
+	p := newParser(src, filename)
+	findings := errorFindings(RuleSyntax, p.parse())
+	findings = append(findings, errorFindings(RuleDanglingRoad, p.checkDirectionValuesExistence())...)
+	if len(findings) == 0 {
+		findings = lintParsedCities(p.parsedCities)
+	}
+	sortFindings(findings)
+	return findings
+}
+
+// errorFindings converts errors to findings with error severity
+func errorFindings(rule string, err error) []Finding {
+	var findings []Finding
+	for _, e := range collectErrors(nil, err) {
+		f := Finding{Severity: SeverityError, Rule: rule, Message: e.Error()}
+		var pe parserError
+		if errors.As(e, &pe) {
+			f.Position, f.Message = pe.position, pe.message
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// sortFindings sorts findings by their positions
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].Position, findings[j].Position
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// lintParsedCities checks cities without syntax errors and dangling roads with all lint rules
+func lintParsedCities(cities map[string]*parsedCity) []Finding {
+	names := make([]string, 0, len(cities))
+	for name := range cities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var findings []Finding
+	findings = append(findings, lintRoads(cities, names)...)
+	findings = append(findings, lintIsolation(cities, names)...)
+	findings = append(findings, lintConnectivity(cities, names)...)
+	findings = append(findings, lintClosedNeighbourhoods(cities, names)...)
+	findings = append(findings, lintGeometry(cities, names)...)
+	return findings
+}
+
+// lintRoads finds roads which lead to the city itself and roads without a way back
+func lintRoads(cities map[string]*parsedCity, names []string) []Finding {
+	var findings []Finding
+	for _, name := range names {
+		c := cities[name]
+		for _, d := range c.getDirections() {
+			position := c.positions[d.directionType]
+			if d.directionValue == name {
+				findings = append(findings, Finding{
+					Severity: SeverityWarning,
+					Rule:     RuleRoadToItself,
+					Position: position,
+					Message:  fmt.Sprintf("road %s of city %s leads to the city itself", d.directionType, name),
+				})
+				continue
+			}
+			opposite := c.topology.Opposite(d.directionType)
+			if opposite == "" {
+				continue
+			}
+			back := cities[d.directionValue].getDirection(opposite)
+			switch back {
+			case name:
+			case "":
+				findings = append(findings, Finding{
+					Severity: SeverityWarning,
+					Rule:     RuleAsymmetricRoad,
+					Position: position,
+					Message:  fmt.Sprintf("city %s has %s=%s, but %s has no %s=%s", name, d.directionType, d.directionValue, d.directionValue, opposite, name),
+				})
+			default:
+				findings = append(findings, Finding{
+					Severity: SeverityWarning,
+					Rule:     RuleAsymmetricRoad,
+					Position: position,
+					Message:  fmt.Sprintf("city %s has %s=%s, but %s has %s=%s", name, d.directionType, d.directionValue, d.directionValue, opposite, back),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// incomingRoads counts roads which lead to every city from other cities
+func incomingRoads(cities map[string]*parsedCity) map[string]int {
+	incoming := make(map[string]int, len(cities))
+	for name, c := range cities {
+		for _, d := range c.getDirections() {
+			if d.directionValue != name {
+				incoming[d.directionValue]++
+			}
+		}
+	}
+	return incoming
+}
+
+// lintIsolation finds cities which aliens can't reach and cities which are not connected to other cities at all
+func lintIsolation(cities map[string]*parsedCity, names []string) []Finding {
+	incoming := incomingRoads(cities)
+	var findings []Finding
+	for _, name := range names {
+		c := cities[name]
+		if incoming[name] != 0 {
+			continue
+		}
+		outgoing := 0
+		for _, d := range c.getDirections() {
+			if d.directionValue != name {
+				outgoing++
+			}
+		}
+		if outgoing == 0 {
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Rule:     RuleIsolatedCity,
+				Position: c.position,
+				Message:  fmt.Sprintf("city %s is not connected to any other city", name),
+			})
+			continue
+		}
+		findings = append(findings, Finding{
+			Severity: SeverityInfo,
+			Rule:     RuleUnreachableCity,
+			Position: c.position,
+			Message:  fmt.Sprintf("no road leads to city %s, aliens can only land in it", name),
+		})
+	}
+	return findings
+}
+
+// lintConnectivity finds parts of the map which are not connected with each other by roads in any direction.
+// The biggest part is considered the main one, every other part is reported
+func lintConnectivity(cities map[string]*parsedCity, names []string) []Finding {
+	// roads are considered two-way to find connected parts
+	neighbours := make(map[string][]string, len(cities))
+	for _, name := range names {
+		for _, d := range cities[name].getDirections() {
+			neighbours[name] = append(neighbours[name], d.directionValue)
+			neighbours[d.directionValue] = append(neighbours[d.directionValue], name)
+		}
+	}
+	visited := make(map[string]bool, len(cities))
+	var components [][]string
+	for _, name := range names {
+		if visited[name] {
+			continue
+		}
+		component := []string{name}
+		visited[name] = true
+		for i := 0; i < len(component); i++ {
+			for _, n := range neighbours[component[i]] {
+				if !visited[n] {
+					visited[n] = true
+					component = append(component, n)
+				}
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+	if len(components) < 2 {
+		return nil
+	}
+	// components are found in alphabetical order of their first cities, so the order is stable
+	sort.SliceStable(components, func(i, j int) bool { return len(components[i]) > len(components[j]) })
+
+	var findings []Finding
+	for _, component := range components[1:] {
+		// isolated cities are reported by their own rule
+		if len(component) == 1 {
+			continue
+		}
+		// the part is reported at the city declared first in the file
+		first := cities[component[0]]
+		for _, name := range component {
+			if cities[name].line < first.line {
+				first = cities[name]
+			}
+		}
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Rule:     RuleDisconnectedMap,
+			Position: first.position,
+			Message: fmt.Sprintf("cities %s are disconnected from the rest of the map of %d cities",
+				strings.Join(component, ", "), len(components[0])),
+		})
+	}
+	return findings
+}
+
+// lintClosedNeighbourhoods finds cities with all directions of the topology set which lead to a neighbourhood
+// aliens can't leave: every road of every neighbour leads back to the city or to another neighbour
+func lintClosedNeighbourhoods(cities map[string]*parsedCity, names []string) []Finding {
+	var findings []Finding
+	for _, name := range names {
+		c := cities[name]
+		directions := c.getDirections()
+		if len(directions) != len(c.topology.directions) {
+			continue
+		}
+		neighbourhood := map[string]bool{name: true}
+		for _, d := range directions {
+			neighbourhood[d.directionValue] = true
+		}
+		closed := true
+		for _, d := range directions {
+			for _, nd := range cities[d.directionValue].getDirections() {
+				if !neighbourhood[nd.directionValue] {
+					closed = false
+				}
+			}
+		}
+		if !closed || len(neighbourhood) == len(cities) {
+			continue
+		}
+		findings = append(findings, Finding{
+			Severity: SeverityInfo,
+			Rule:     RuleClosedNeighbourhood,
+			Position: c.position,
+			Message:  fmt.Sprintf("all %d roads of city %s lead to a closed neighbourhood, aliens can't leave it", len(directions), name),
+		})
+	}
+	return findings
+}
+
+// lintGeometry finds roads whose directions contradict each other, for example a city which is north of another one
+// and also south of it via other roads, and cities which roads put on the same place of the map
+func lintGeometry(cities map[string]*parsedCity, names []string) []Finding {
+	if len(names) == 0 {
+		return nil
+	}
+	// roads without the right way back are reported as asymmetric roads, so they are left out of the geometry
+	l := layout.Compute(names, layoutLinks(cities[names[0]].topology, names, func(name string) []mapDirection {
+		var directions []mapDirection
+		for _, d := range cities[name].getDirections() {
+			opposite := cities[name].topology.Opposite(d.directionType)
+			if opposite == "" || cities[d.directionValue].getDirection(opposite) == name {
+				directions = append(directions, d)
+			}
+		}
+		return directions
+	}))
+	var findings []Finding
+	reported := map[[2]string]bool{}
+	for _, c := range l.Conflicts {
+		// the way back of a road contradicts the same roads
+		pair := [2]string{c.Link.From, c.Link.To}
+		if pair[0] > pair[1] {
+			pair[0], pair[1] = pair[1], pair[0]
+		}
+		if reported[pair] {
+			continue
+		}
+		reported[pair] = true
+		path := make([]string, 0, len(c.Path))
+		for _, r := range c.Path {
+			path = append(path, fmt.Sprintf("%s %s=%s", r.From, r.Direction, r.To))
+		}
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Rule:     RuleInconsistentGeometry,
+			Position: cities[c.Link.From].positions[c.Link.Direction],
+			Message: fmt.Sprintf("city %s has %s=%s, which contradicts roads %s",
+				c.Link.From, c.Link.Direction, c.Link.To, strings.Join(path, ", ")),
+		})
+	}
+	for _, overlap := range l.Overlaps {
+		// the overlap is reported at the city declared last in the file
+		last := cities[overlap[0]]
+		for _, name := range overlap {
+			if cities[name].line > last.line {
+				last = cities[name]
+			}
+		}
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Rule:     RuleInconsistentGeometry,
+			Position: last.position,
+			Message:  fmt.Sprintf("roads put cities %s on the same place of the map", strings.Join(overlap, ", ")),
+		})
+	}
+	return findings
+}
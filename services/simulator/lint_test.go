@@ -0,0 +1,131 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// lintInput lints the input and returns findings as strings
+func lintInput(input string) []string {
+	var findings []string
+	for _, f := range lintMap(strings.NewReader(input), "testing") {
+		findings = append(findings, f.String())
+	}
+	return findings
+}
+
+func TestLintReportsParserErrors(t *testing.T) {
+	input := `London west=Boston
+Bolton nowhere=London
+Paris north=Paris`
+	require.Equal(t, []string{
+		"testing:1:19: error: city London has direction west which points to non existent city Boston [dangling-road]",
+		"testing:2:15: error: got unexpected mapDirection type nowhere, expected one of south,north,west,east [syntax]",
+	}, lintInput(input))
+}
+
+func TestLintAcceptsConsistentMap(t *testing.T) {
+	input := `London east=Bolton south=Paris
+Bolton west=London
+Paris north=London`
+	require.Empty(t, lintInput(input))
+}
+
+func TestLintReportsAsymmetricRoads(t *testing.T) {
+	input := `London east=Bolton south=Paris
+Bolton north=London
+Paris north=Bolton`
+	require.Equal(t, []string{
+		"testing:1:19: warning: city London has east=Bolton, but Bolton has no west=London [asymmetric-road]",
+		"testing:1:31: warning: city London has south=Paris, but Paris has north=Bolton [asymmetric-road]",
+		"testing:2:20: warning: city Bolton has north=London, but London has south=Paris [asymmetric-road]",
+		"testing:3:19: warning: city Paris has north=Bolton, but Bolton has no south=Paris [asymmetric-road]",
+	}, lintInput(input))
+}
+
+func TestLintReportsIsolatedAndUnreachableCities(t *testing.T) {
+	input := `London east=Bolton
+Bolton west=London
+Rome north=Rome
+Paris north=London`
+	require.Equal(t, []string{
+		"testing:3:5: warning: city Rome is not connected to any other city [isolated-city]",
+		"testing:3:16: warning: road north of city Rome leads to the city itself [road-to-itself]",
+		"testing:4:6: info: no road leads to city Paris, aliens can only land in it [unreachable-city]",
+		"testing:4:19: warning: city Paris has north=London, but London has no south=Paris [asymmetric-road]",
+	}, lintInput(input))
+}
+
+func TestLintReportsDisconnectedParts(t *testing.T) {
+	input := `London east=Bolton
+Bolton west=London east=Leeds
+Leeds west=Bolton
+Paris east=Berlin
+Berlin west=Paris`
+	require.Equal(t, []string{
+		"testing:4:6: warning: cities Berlin, Paris are disconnected from the rest of the map of 3 cities [disconnected-map]",
+	}, lintInput(input))
+}
+
+func TestLintReportsClosedNeighbourhoods(t *testing.T) {
+	input := `Hub north=N east=E south=S west=W
+N south=Hub east=E
+E west=Hub north=N
+S north=Hub
+W east=Hub
+Outside west=S`
+	require.Contains(t, lintInput(input),
+		"testing:1:4: info: all 4 roads of city Hub lead to a closed neighbourhood, aliens can't leave it [closed-neighbourhood]")
+}
+
+func TestFindingString(t *testing.T) {
+	f := Finding{Severity: SeverityWarning, Rule: RuleIsolatedCity, Message: "city Rome is not connected to any other city"}
+	f.Position.Filename, f.Position.Line, f.Position.Column = "earth.emap", 3, 5
+	require.Equal(t, "earth.emap:3:5: warning: city Rome is not connected to any other city [isolated-city]", f.String())
+}
+
+func TestLintUsesMapTopology(t *testing.T) {
+	input := `@topology hex
+Paris northeast=Berlin east=Rome
+Berlin southwest=Paris
+Rome west=Paris northwest=Berlin
+`
+	findings := lintMap(strings.NewReader(input), "testing")
+	require.Len(t, findings, 1)
+	require.Equal(t, "testing:4:33: warning: city Rome has northwest=Berlin, but Berlin has no southeast=Rome [asymmetric-road]", findings[0].String())
+}
+
+func TestLintReportsInconsistentGeometry(t *testing.T) {
+	// Oslo is north of Paris and also south of it via Rome and Milan
+	input := `Paris north=Oslo east=Rome
+Rome south=Milan west=Paris
+Milan north=Rome west=Oslo
+Oslo east=Milan south=Paris`
+	require.Equal(t, []string{
+		"testing:4:28: warning: city Oslo has south=Paris, which contradicts roads Milan west=Oslo, Milan north=Rome, Paris east=Rome [inconsistent-geometry]",
+	}, lintInput(input))
+}
+
+func TestLintReportsAsymmetricRoadsOnce(t *testing.T) {
+	// one-way roads contradict each other too, but they are reported only as asymmetric roads
+	input := `A east=B
+B south=C
+C west=A`
+	for _, f := range lintMap(strings.NewReader(input), "testing") {
+		require.NotEqual(t, RuleInconsistentGeometry, f.Rule, f.String())
+	}
+}
+
+func TestLintReportsCitiesOnTheSamePlace(t *testing.T) {
+	// the road around the corner leads back to the place of Paris
+	input := `Paris east=Rome
+Rome west=Paris south=Milan
+Milan north=Rome west=Lyon
+Lyon east=Milan north=Oslo
+Oslo south=Lyon`
+	require.Equal(t, []string{
+		"testing:5:5: warning: roads put cities Oslo, Paris on the same place of the map [inconsistent-geometry]",
+	}, lintInput(input))
+}
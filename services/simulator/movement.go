@@ -0,0 +1,192 @@
+package simulator
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// AlienInfo is a read-only snapshot of an alien which is given to strategies
+type AlienInfo struct {
+	ID      int64  `json:"id"`
+	City    string `json:"city"`
+	Dead    bool   `json:"dead"`
+	Trapped bool   `json:"trapped"`
+	// Transit is the number of turns left before the alien arrives in its city
+	Transit int `json:"transit,omitempty"`
+
+	visited map[string]int
+}
+
+// Visits returns how many times the alien has been in the city
+func (a AlienInfo) Visits(cityName string) int {
+	return a.visited[cityName]
+}
+
+// MovementStrategy decides where an alien moves every turn
+type MovementStrategy interface {
+	// Move picks one of the provided live roads of the alien's city.
+	// Returning false keeps the alien in its city for the turn
+	Move(a AlienInfo, roads []Road, m MapView, rnd *rand.Rand) (Road, bool)
+}
+
+// RandomWalk moves an alien along a road chosen uniformly at random
+type RandomWalk struct{}
+
+func (RandomWalk) Move(_ AlienInfo, roads []Road, _ MapView, rnd *rand.Rand) (Road, bool) {
+	return roads[rnd.Intn(len(roads))], true
+}
+
+// Flocking moves an alien to the neighbour with the biggest number of aliens, ties are broken randomly
+type Flocking struct{}
+
+func (Flocking) Move(_ AlienInfo, roads []Road, m MapView, rnd *rand.Rand) (Road, bool) {
+	return pickBest(roads, rnd, func(r Road) int { return m.Aliens(r.City) }), true
+}
+
+// Explorer moves an alien to the neighbour it has visited the least number of times, ties are broken randomly
+type Explorer struct{}
+
+func (Explorer) Move(a AlienInfo, roads []Road, _ MapView, rnd *rand.Rand) (Road, bool) {
+	return pickBest(roads, rnd, func(r Road) int { return -a.Visits(r.City) }), true
+}
+
+// StayPut keeps an alien in its city with the provided probability, otherwise the alien walks randomly
+type StayPut struct {
+	Probability float64
+}
+
+func (s StayPut) Move(a AlienInfo, roads []Road, m MapView, rnd *rand.Rand) (Road, bool) {
+	if rnd.Float64() < s.Probability {
+		return Road{}, false
+	}
+	return RandomWalk{}.Move(a, roads, m, rnd)
+}
+
+// CompassBias moves an alien randomly, but a road in the preferred direction is Weight times
+// more likely to be chosen than any other road
+type CompassBias struct {
+	Direction string
+	Weight    float64
+}
+
+func (c CompassBias) Move(_ AlienInfo, roads []Road, _ MapView, rnd *rand.Rand) (Road, bool) {
+	return pickWeighted(roads, rnd, c.weight), true
+}
+
+// weight returns the weight of the road
+func (c CompassBias) weight(r Road) float64 {
+	if r.Direction == c.Direction {
+		return c.Weight
+	}
+	return 1
+}
+
+// CheapRoads moves an alien randomly preferring short roads, a road is chosen with the probability
+// inversely proportional to the number of turns an alien needs to travel along it
+type CheapRoads struct{}
+
+func (CheapRoads) Move(_ AlienInfo, roads []Road, _ MapView, rnd *rand.Rand) (Road, bool) {
+	return pickWeighted(roads, rnd, func(r Road) float64 { return 1 / float64(r.Turns()) }), true
+}
+
+// pickWeighted returns a random road, the probability to choose a road is proportional to its weight
+func pickWeighted(roads []Road, rnd *rand.Rand, weight func(Road) float64) Road {
+	total := 0.0
+	for _, r := range roads {
+		total += weight(r)
+	}
+	x := rnd.Float64() * total
+	for _, r := range roads {
+		x -= weight(r)
+		if x < 0 {
+			return r
+		}
+	}
+	return roads[len(roads)-1]
+}
+
+// pickBest returns a road with the highest score, ties are broken randomly
+func pickBest(roads []Road, rnd *rand.Rand, score func(Road) int) Road {
+	var best []Road
+	bestScore := 0
+	for _, r := range roads {
+		s := score(r)
+		switch {
+		case len(best) == 0 || s > bestScore:
+			best = append(best[:0], r)
+			bestScore = s
+		case s == bestScore:
+			best = append(best, r)
+		}
+	}
+	return best[rnd.Intn(len(best))]
+}
+
+// containsRoad checks whether roads contain the road or not
+func containsRoad(roads []Road, road Road) bool {
+	for _, r := range roads {
+		if r == road {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMovementStrategy creates a movement strategy from its textual description. Supported descriptions are
+// random, flock, explore, cheap, stay:probability and bias:direction:weight
+func ParseMovementStrategy(spec string) (MovementStrategy, error) {
+	parts := strings.Split(spec, ":")
+	expectArgs := func(n int) error {
+		if len(parts)-1 != n {
+			return fmt.Errorf("movement strategy %s expects %d arguments, got %s", parts[0], n, spec)
+		}
+		return nil
+	}
+	switch parts[0] {
+	case "random":
+		if err := expectArgs(0); err != nil {
+			return nil, err
+		}
+		return RandomWalk{}, nil
+	case "flock":
+		if err := expectArgs(0); err != nil {
+			return nil, err
+		}
+		return Flocking{}, nil
+	case "explore":
+		if err := expectArgs(0); err != nil {
+			return nil, err
+		}
+		return Explorer{}, nil
+	case "cheap":
+		if err := expectArgs(0); err != nil {
+			return nil, err
+		}
+		return CheapRoads{}, nil
+	case "stay":
+		if err := expectArgs(1); err != nil {
+			return nil, err
+		}
+		p, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || p < 0 || p > 1 {
+			return nil, fmt.Errorf("probability to stay must be a number between 0 and 1, got %s", parts[1])
+		}
+		return StayPut{Probability: p}, nil
+	case "bias":
+		if err := expectArgs(2); err != nil {
+			return nil, err
+		}
+		if !isValidDirection(parts[1]) {
+			return nil, fmt.Errorf("got unexpected bias direction %s, expected a valid direction name", parts[1])
+		}
+		w, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil || w <= 0 {
+			return nil, fmt.Errorf("bias weight must be a positive number, got %s", parts[2])
+		}
+		return CompassBias{Direction: parts[1], Weight: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown movement strategy %s, expected one of random,flock,explore,cheap,stay,bias", parts[0])
+	}
+}
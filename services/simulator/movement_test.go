@@ -0,0 +1,154 @@
+package simulator
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// crossroads returns a map where Center has a road in every direction and numbers of aliens in the neighbours
+func crossroads() (planetMap, []Road) {
+	roads := []Road{
+		{Direction: "north", City: "North"},
+		{Direction: "east", City: "East"},
+		{Direction: "south", City: "South"},
+		{Direction: "west", City: "West"},
+	}
+	p := planetMap{"Center": {name: "Center"}}
+	for _, r := range roads {
+		p["Center"].directions = append(p["Center"].directions, mapDirection{directionType: r.Direction, directionValue: r.City})
+		p[r.City] = &city{name: r.City}
+	}
+	p["East"].aliens = []int64{1, 2}
+	p["West"].aliens = []int64{3}
+	return p, roads
+}
+
+func TestFlockingMovesToTheMostPopulousNeighbour(t *testing.T) {
+	p, roads := crossroads()
+	view := newMapView(p, p.sortedNames())
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		road, ok := Flocking{}.Move(AlienInfo{City: "Center"}, roads, view, rnd)
+		require.True(t, ok)
+		require.Equal(t, Road{Direction: "east", City: "East"}, road)
+	}
+}
+
+func TestExplorerAvoidsVisitedCities(t *testing.T) {
+	p, roads := crossroads()
+	view := newMapView(p, p.sortedNames())
+	rnd := rand.New(rand.NewSource(1))
+	a := AlienInfo{City: "Center", visited: map[string]int{"North": 2, "East": 1, "West": 1}}
+	for i := 0; i < 20; i++ {
+		road, ok := Explorer{}.Move(a, roads, view, rnd)
+		require.True(t, ok)
+		require.Equal(t, "South", road.City)
+	}
+	a.visited["South"] = 1
+	counts := map[string]int{}
+	for i := 0; i < 300; i++ {
+		road, _ := Explorer{}.Move(a, roads, view, rnd)
+		counts[road.City]++
+	}
+	require.ElementsMatch(t, []string{"East", "South", "West"}, keys(counts))
+}
+
+func TestStayPutKeepsAlienWithProbability(t *testing.T) {
+	p, roads := crossroads()
+	view := newMapView(p, p.sortedNames())
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		_, ok := StayPut{Probability: 1}.Move(AlienInfo{City: "Center"}, roads, view, rnd)
+		require.False(t, ok)
+		_, ok = StayPut{Probability: 0}.Move(AlienInfo{City: "Center"}, roads, view, rnd)
+		require.True(t, ok)
+	}
+	stays := 0
+	for i := 0; i < 10000; i++ {
+		if _, ok := (StayPut{Probability: 0.3}).Move(AlienInfo{City: "Center"}, roads, view, rnd); !ok {
+			stays++
+		}
+	}
+	require.InDelta(t, 3000, stays, 300)
+}
+
+func TestCompassBiasPrefersDirection(t *testing.T) {
+	p, roads := crossroads()
+	view := newMapView(p, p.sortedNames())
+	rnd := rand.New(rand.NewSource(1))
+	counts := map[string]int{}
+	for i := 0; i < 12000; i++ {
+		road, ok := CompassBias{Direction: "north", Weight: 3}.Move(AlienInfo{City: "Center"}, roads, view, rnd)
+		require.True(t, ok)
+		counts[road.Direction]++
+	}
+	// north has weight 3 and the other directions weight 1, so north is chosen in a half of cases
+	require.InDelta(t, 6000, counts["north"], 400)
+	require.InDelta(t, 2000, counts["south"], 400)
+}
+
+// teleport is a movement strategy which always picks a road which doesn't exist
+type teleport struct{}
+
+func (teleport) Move(AlienInfo, []Road, MapView, *rand.Rand) (Road, bool) {
+	return Road{Direction: "north", City: "Atlantis"}, true
+}
+
+func TestAlienStaysWhenStrategyPicksInvalidRoad(t *testing.T) {
+	p, _ := crossroads()
+	a := &alien{city: "Center"}
+	p["Center"].addAlien(0)
+	_, outcome := p.moveAlien(0, a, teleport{}, newMapView(p, p.sortedNames()), rand.New(rand.NewSource(1)))
+	require.Equal(t, alienStayed, outcome)
+	require.Equal(t, "Center", a.city)
+	require.Equal(t, []int64{0}, p["Center"].aliens)
+}
+
+func TestParseMovementStrategy(t *testing.T) {
+	cases := []struct {
+		spec     string
+		expected MovementStrategy
+		err      string
+	}{
+		{spec: "random", expected: RandomWalk{}},
+		{spec: "flock", expected: Flocking{}},
+		{spec: "explore", expected: Explorer{}},
+		{spec: "cheap", expected: CheapRoads{}},
+		{spec: "stay:0.25", expected: StayPut{Probability: 0.25}},
+		{spec: "bias:west:2.5", expected: CompassBias{Direction: "west", Weight: 2.5}},
+		{spec: "random:1", err: "movement strategy random expects 0 arguments, got random:1"},
+		{spec: "stay", err: "movement strategy stay expects 1 arguments, got stay"},
+		{spec: "stay:2", err: "probability to stay must be a number between 0 and 1, got 2"},
+		{spec: "bias:1up:2", err: "got unexpected bias direction 1up, expected a valid direction name"},
+		{spec: "bias:west:0", err: "bias weight must be a positive number, got 0"},
+		{spec: "teleport", err: "unknown movement strategy teleport, expected one of random,flock,explore,cheap,stay,bias"},
+	}
+	for _, tc := range cases {
+		strategy, err := ParseMovementStrategy(tc.spec)
+		if tc.err != "" {
+			require.EqualError(t, err, tc.err, tc.spec)
+			continue
+		}
+		require.Nil(t, err, tc.spec)
+		require.Equal(t, tc.expected, strategy, tc.spec)
+	}
+}
+
+func TestCheapRoadsPreferShortRoads(t *testing.T) {
+	roads := []Road{
+		{Direction: "north", City: "North"},
+		{Direction: "east", City: "East", Transit: 2},
+	}
+	require.Equal(t, 3, roads[1].Turns())
+	rnd := rand.New(rand.NewSource(1))
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		road, ok := CheapRoads{}.Move(AlienInfo{}, roads, nil, rnd)
+		require.True(t, ok)
+		counts[road.City]++
+	}
+	require.InDelta(t, 3000, counts["North"], 150)
+	require.InDelta(t, 1000, counts["East"], 150)
+}
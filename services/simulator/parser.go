@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
+	"io/ioutil"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"text/scanner"
-	"unicode"
 )
 
+// directions of the default topology
 const (
 	directionSouth = "south"
 	directionNorth = "north"
@@ -19,75 +22,95 @@ const (
 
 // parsedCity represents a city parsed from a map file
 type parsedCity struct {
-	line                     int
-	north, east, south, west string
+	line int
+	// directions of the city by direction types
+	roads map[string]string
+	// topology of the map the city belongs to
+	topology *Topology
+	// attributes of the city, for example its population
+	attributes Attributes
+	// weights of roads by direction types, roads without a weight take one turn
+	weights map[string]int
+
+	// position of the city name
+	position scanner.Position
+
+	// comment which follows directions of the city
+	comment string
+
+	// positions of direction values by direction types
+	positions map[string]scanner.Position
 }
 
 // directionExists checks whether city has direction to city called cityName or not
 func (pc *parsedCity) directionExists(cityName string) bool {
-	return pc.north == cityName || pc.east == cityName || pc.south == cityName || pc.west == cityName
+	for _, value := range pc.roads {
+		if value == cityName {
+			return true
+		}
+	}
+	return false
 }
 
 // isDirectionSet checks whether city has set direction with directionType or not
 func (pc *parsedCity) isDirectionSet(directionType string) bool {
-	switch directionType {
-	case directionSouth:
-		return pc.south != ""
-	case directionNorth:
-		return pc.north != ""
-	case directionEast:
-		return pc.east != ""
-	case directionWest:
-		return pc.west != ""
-	default:
-		return false
-	}
+	return pc.roads[directionType] != ""
+}
+
+// getDirection returns value of the direction with provided direction type
+func (pc *parsedCity) getDirection(directionType string) string {
+	return pc.roads[directionType]
 }
 
 // setDirection sets direction with provided direction and value
 func (pc *parsedCity) setDirection(direction, value string) {
-	switch direction {
-	case directionSouth:
-		pc.south = value
-	case directionNorth:
-		pc.north = value
-	case directionEast:
-		pc.east = value
-	case directionWest:
-		pc.west = value
+	pc.roads[direction] = value
+}
+
+// transit returns the number of turns an alien spends on the road after the first one
+func (pc *parsedCity) transit(directionType string) int {
+	if weight := pc.weights[directionType]; weight > 1 {
+		return weight - 1
 	}
+	return 0
 }
 
-// getDirections returns list of all city directions
+// getDirections returns list of all city directions in the order of the topology directions
 func (pc *parsedCity) getDirections() []mapDirection {
-	directions := make([]mapDirection, 0, 4)
-	if pc.south != "" {
-		directions = append(directions, mapDirection{
-			directionType:  directionSouth,
-			directionValue: pc.south,
-		})
-	}
-	if pc.north != "" {
-		directions = append(directions, mapDirection{
-			directionType:  directionNorth,
-			directionValue: pc.north,
-		})
-	}
-	if pc.west != "" {
-		directions = append(directions, mapDirection{
-			directionType:  directionWest,
-			directionValue: pc.west,
-		})
-	}
-	if pc.east != "" {
-		directions = append(directions, mapDirection{
-			directionType:  directionEast,
-			directionValue: pc.east,
-		})
+	directions := make([]mapDirection, 0, len(pc.roads))
+	for _, d := range pc.topology.directions {
+		if value := pc.roads[d]; value != "" {
+			directions = append(directions, mapDirection{
+				directionType:  d,
+				directionValue: value,
+				transit:        pc.transit(d),
+			})
+		}
 	}
 	return directions
 }
 
+// commentSign starts a comment which lasts until the end of the line
+const commentSign = '#'
+
+type layoutKind byte
+
+const (
+	layoutCity layoutKind = iota
+	layoutComment
+	layoutBlank
+	layoutTopology
+	layoutAttribute
+)
+
+// layoutLine is a line of a map file, it keeps a city name, a comment text or a name of a declared attribute
+type layoutLine struct {
+	kind layoutKind
+	text string
+	// comment which follows a directive
+	comment string
+}
+
 type expectation byte
 
 const (
@@ -95,6 +118,7 @@ const (
 	expectDirectionType
 	expectDirectionValue
 	expectEqualSign
+	expectAttributeValue
 )
 
 // parserError may be returned during a parsing process
@@ -104,6 +128,13 @@ type parserError struct {
 }
 
 func (p parserError) Error() string {
+	// problems of map documents have no position in the file, their messages start with a path in the document
+	if !p.position.IsValid() {
+		if p.position.Filename == "" {
+			return p.message
+		}
+		return fmt.Sprintf("%s: %s", p.position.Filename, p.message)
+	}
 	return fmt.Sprintf("%s:%d:%d: %s", p.position.Filename, p.position.Line, p.position.Column, p.message)
 }
 
@@ -115,14 +146,69 @@ func newParserError(position scanner.Position, msg string) error {
 	}
 }
 
+// MapErrors is a list of all problems found in a map file sorted by their positions
+type MapErrors []error
+
+func (m MapErrors) Error() string {
+	messages := make([]string, len(m))
+	for i, err := range m {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// sort sorts errors by their positions, errors without a position go first
+func (m MapErrors) sort() {
+	sort.SliceStable(m, func(i, j int) bool {
+		a, aOk := m[i].(parserError)
+		b, bOk := m[j].(parserError)
+		if !aOk || !bOk {
+			return !aOk && bOk
+		}
+		if a.position.Line != b.position.Line {
+			return a.position.Line < b.position.Line
+		}
+		return a.position.Column < b.position.Column
+	})
+}
+
+// err returns sorted errors or nil if there are no errors
+func (m MapErrors) err() error {
+	if len(m) == 0 {
+		return nil
+	}
+	m.sort()
+	return m
+}
+
 // parser parses map input and creates Simulation
 type parser struct {
 	parsed             bool
 	currentExpectation expectation
 	currentCity        string
 	currentDirection   string
+	currentAttribute   string
 	s                  scanner.Scanner
 	parsedCities       map[string]*parsedCity
+	// topology of the map, the default one unless the map declares another
+	topology *Topology
+	// line of the topology declaration, 0 if the map doesn't declare a topology
+	topologyLine int
+	// attributes cities of the map may have
+	schema *attributeSchema
+
+	// specifies either the current line declares a city or it is blank or a comment
+	lineHasCity bool
+	// specifies either the current line has a comment
+	lineHasComment bool
+	// specifies either the current line has a directive
+	lineHasDirective bool
+	// order of cities, comments and blank lines in the input
+	layout []layoutLine
+	// all problems found during parsing
+	errs MapErrors
+	// the last error reported by the scanner
+	scanError string
 }
 
 // newParser creates new parser with provided input and filename.
@@ -130,12 +216,18 @@ type parser struct {
 func newParser(src io.Reader, filename string) *parser {
 	p := &parser{
 		parsedCities: map[string]*parsedCity{},
+		topology:     DefaultTopology(),
+		schema:       newAttributeSchema(),
 	}
 	p.s.Init(src)
 	p.s.Filename = filename
 	p.s.Whitespace ^= 1 << '\n'
 	p.s.IsIdentRune = func(ch rune, i int) bool {
-		return ch != '=' && (ch >= '!' && ch <= '~' || unicode.IsLetter(ch))
+		return ch != '=' && ch != commentSign && ch != '"' && (ch >= '!' && ch <= '~' || isCityNameRune(ch))
+	}
+	// scanner errors, for example an unterminated quoted name, are reported by the parser
+	p.s.Error = func(s *scanner.Scanner, msg string) {
+		p.scanError = msg
 	}
 	return p
 }
@@ -145,7 +237,63 @@ func (p *parser) currentCityHasAtLeastOneDirection() bool {
 	return len(p.parsedCities[p.currentCity].getDirections()) != 0
 }
 
-// parse parses input
+// skipLine skips the rest of the line and returns skipped text. The newline itself is left to the scanner
+func (p *parser) skipLine() string {
+	builder := strings.Builder{}
+	for ch := p.s.Peek(); ch != '\n' && ch != scanner.EOF; ch = p.s.Peek() {
+		builder.WriteRune(p.s.Next())
+	}
+	return builder.String()
+}
+
+// handleComment remembers the comment either as a comment of the current city or as a separate line
+func (p *parser) handleComment() {
+	text := p.skipLine()
+	p.lineHasComment = true
+	if p.lineHasCity {
+		p.parsedCities[p.currentCity].comment = text
+		return
+	}
+	p.layout = append(p.layout, layoutLine{kind: layoutComment, text: text})
+}
+
+// nextLine resets parser expectation for a new line
+func (p *parser) nextLine() {
+	p.currentExpectation = expectCity
+	p.currentAttribute = ""
+	p.lineHasCity = false
+	p.lineHasComment = false
+	p.lineHasDirective = false
+}
+
+// recover remembers the error and skips the rest of the line, so parsing goes on from the next line
+func (p *parser) recover(err error) {
+	p.errs = append(p.errs, err)
+	p.skipLine()
+	p.nextLine()
+}
+
+// handleScanError reports the scanner error at the beginning of the broken token. An unterminated quoted name
+// swallows the newline, so the rest of the line is skipped only if the scanner is still on the same line
+func (p *parser) handleScanError() {
+	err := newParserError(p.s.Position, p.scanError)
+	p.scanError = ""
+	if p.s.Pos().Line == p.s.Position.Line {
+		p.recover(err)
+		return
+	}
+	p.errs = append(p.errs, err)
+	p.nextLine()
+}
+
+// expectsDirectionPart checks whether parser is in the middle of a direction or not
+func (p *parser) expectsDirectionPart() bool {
+	return p.currentExpectation == expectEqualSign || p.currentExpectation == expectDirectionValue ||
+		p.currentExpectation == expectAttributeValue
+}
+
+// parse parses input. Parsing doesn't stop on the first problem, the rest of a broken line is skipped
+// and all problems are returned as MapErrors
 func (p *parser) parse() error {
 	if p.parsed {
 		return errors.New("already parsed")
@@ -153,29 +301,43 @@ func (p *parser) parse() error {
 
 	for tok := p.s.Scan(); tok != scanner.EOF; tok = p.s.Scan() {
 		switch tok {
+		case commentSign:
+			p.handleComment()
 		case '\n':
-			if !p.currentCityHasAtLeastOneDirection() {
-				return newParserError(p.s.Pos(), "unexpected newline, city must contain at least one direction")
+			// blank lines and lines with only a comment are skipped
+			if !p.lineHasCity {
+				if !p.lineHasComment && !p.lineHasDirective {
+					p.layout = append(p.layout, layoutLine{kind: layoutBlank})
+				}
+				p.nextLine()
+				continue
 			}
-			p.currentExpectation = expectCity
+			if p.expectsDirectionPart() {
+				p.errs = append(p.errs, newParserError(p.s.Pos(), "unexpected newline, direction must have a value"))
+			} else if !p.currentCityHasAtLeastOneDirection() {
+				p.errs = append(p.errs, newParserError(p.s.Pos(), "unexpected newline, city must contain at least one direction"))
+			}
+			p.nextLine()
 		default:
+			if p.scanError != "" {
+				p.handleScanError()
+				continue
+			}
 			err := p.handleToken(p.s.TokenText())
 			if err != nil {
-				return err
+				p.recover(err)
 			}
 		}
 	}
 
-	if p.currentExpectation == expectEqualSign || p.currentExpectation == expectDirectionValue {
-		return newParserError(p.s.Position, "Unexpected EOF")
-	}
-
-	if !p.currentCityHasAtLeastOneDirection() {
-		return newParserError(p.s.Pos(), "unexpected EOF, city must contain at least one direction")
+	if p.expectsDirectionPart() {
+		p.errs = append(p.errs, newParserError(p.s.Position, "Unexpected EOF"))
+	} else if p.lineHasCity && !p.currentCityHasAtLeastOneDirection() {
+		p.errs = append(p.errs, newParserError(p.s.Pos(), "unexpected EOF, city must contain at least one direction"))
 	}
 
 	p.parsed = true
-	return nil
+	return p.errs.err()
 }
 
 // handleToken handles map fie tokens basing on parser expectation
@@ -201,6 +363,11 @@ func (p *parser) handleToken(token string) error {
 		if err != nil {
 			return err
 		}
+	case expectAttributeValue:
+		err := p.handleAttributeValue(token)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -208,51 +375,162 @@ func (p *parser) handleToken(token string) error {
 
 // handleCityToken handles city expectation
 func (p *parser) handleCityToken(token string) error {
-	// check for existence
-	if _, ok := p.parsedCities[token]; ok {
-		return newParserError(p.s.Pos(), fmt.Sprintf("got city duplication for %s previously declared on line %d", token, p.parsedCities[token].line))
-
+	if strings.HasPrefix(token, "@") {
+		return p.handleDirective(token)
 	}
 	// validate city
-	if !isValidCityName(token) {
+	name, ok := cityName(token)
+	if !ok {
 		return newParserError(p.s.Pos(), fmt.Sprintf("expected a valid city name, got %s", token))
 	}
+	// check for existence
+	if _, ok := p.parsedCities[name]; ok {
+		return newParserError(p.s.Pos(), fmt.Sprintf("got city duplication for %s previously declared on line %d", name, p.parsedCities[name].line))
+
+	}
 	// write new city
-	p.parsedCities[token] = &parsedCity{
-		line: p.s.Pos().Line,
+	p.parsedCities[name] = &parsedCity{
+		line:       p.s.Pos().Line,
+		roads:      map[string]string{},
+		weights:    map[string]int{},
+		topology:   p.topology,
+		attributes: Attributes{},
+		position:   p.s.Pos(),
+		positions:  map[string]scanner.Position{},
 	}
-	p.currentCity = token
+	p.currentCity = name
+	p.lineHasCity = true
+	p.layout = append(p.layout, layoutLine{kind: layoutCity, text: name})
 	p.currentExpectation = expectDirectionType
 	return nil
 }
 
+// handleDirective handles a directive in the map header. Directives declare the topology of the map
+// and custom attributes of cities, they must go before the first city
+func (p *parser) handleDirective(token string) error {
+	position := p.s.Pos()
+	if token != topologyDirective && token != attributeDirective {
+		return newParserError(position, fmt.Sprintf("unknown directive %s, expected one of %s,%s", token, topologyDirective, attributeDirective))
+	}
+	if len(p.parsedCities) != 0 {
+		return newParserError(position, fmt.Sprintf("%s must be declared before the first city", strings.TrimPrefix(token, "@")))
+	}
+	spec, comment := p.skipLine(), ""
+	if i := strings.IndexRune(spec, commentSign); i >= 0 {
+		spec, comment = spec[:i], spec[i+1:]
+	}
+	var err error
+	if token == topologyDirective {
+		err = p.handleTopology(position, spec, comment)
+	} else {
+		err = p.handleAttributeDeclaration(spec, comment)
+	}
+	if err != nil {
+		return newParserError(position, err.Error())
+	}
+	p.lineHasDirective = true
+	return nil
+}
+
+// handleTopology handles the topology declaration
+func (p *parser) handleTopology(position scanner.Position, spec, comment string) error {
+	if p.topologyLine != 0 {
+		return fmt.Errorf("got topology duplication, topology is already declared on line %d", p.topologyLine)
+	}
+	topology, err := ParseTopology(spec)
+	if err != nil {
+		return err
+	}
+	for _, d := range topology.directions {
+		if p.schema.has(d) {
+			return fmt.Errorf("direction %s conflicts with attribute %s", d, d)
+		}
+	}
+	p.topology = topology
+	p.topologyLine = position.Line
+	p.layout = append(p.layout, layoutLine{kind: layoutTopology, comment: comment})
+	return nil
+}
+
+// handleAttributeDeclaration handles the declaration of a custom attribute, for example `@attribute airport bool`
+func (p *parser) handleAttributeDeclaration(spec, comment string) error {
+	fields := strings.Fields(spec)
+	if len(fields) != 2 {
+		return fmt.Errorf("expected %s name type, got %s %s", attributeDirective, attributeDirective, strings.TrimSpace(spec))
+	}
+	if p.topology.Has(fields[0]) {
+		return fmt.Errorf("attribute %s conflicts with direction %s", fields[0], fields[0])
+	}
+	if err := p.schema.declare(fields[0], fields[1]); err != nil {
+		return err
+	}
+	p.layout = append(p.layout, layoutLine{kind: layoutAttribute, text: fields[0], comment: comment})
+	return nil
+}
+
 // handleDirectionValue handles direction value expectation
 func (p *parser) handleDirectionValue(token string) error {
+	token, weight, err := p.splitWeight(token)
+	if err != nil {
+		return err
+	}
 	// validate city
-	// micro optimization: try to check mapDirection value against parsed city and avoid usage of regexp
-	if _, ok := p.parsedCities[token]; !ok {
-		if !isValidCityName(token) {
-			return newParserError(p.s.Pos(), fmt.Sprintf("expected a valid city name as a mapDirection value, got %s", token))
-		}
+	name, ok := cityName(token)
+	if !ok {
+		return newParserError(p.s.Pos(), fmt.Sprintf("expected a valid city name as a mapDirection value, got %s", token))
 	}
 	// check against mapDirection value duplication
-	if p.parsedCities[p.currentCity].directionExists(token) {
+	if p.parsedCities[p.currentCity].directionExists(name) {
 		// mapDirection value duplication
-		return newParserError(p.s.Pos(), fmt.Sprintf("got mapDirection value duplication %s for city %s", token, p.currentCity))
+		return newParserError(p.s.Pos(), fmt.Sprintf("got mapDirection value duplication %s for city %s", name, p.currentCity))
 	}
 	// write mapDirection to current city current mapDirection
-	p.parsedCities[p.currentCity].setDirection(p.currentDirection, token)
+	p.parsedCities[p.currentCity].setDirection(p.currentDirection, name)
+	p.parsedCities[p.currentCity].positions[p.currentDirection] = p.s.Pos()
+	if weight != 0 {
+		p.parsedCities[p.currentCity].weights[p.currentDirection] = weight
+	}
 	p.currentExpectation = expectDirectionType
 
 	return nil
 }
 
+// roadWeightSign separates the weight of a road from the city name, for example `north=Berlin:3`
+const roadWeightSign = ':'
+
+// splitWeight splits the direction value into the city name and the weight of the road, 0 is returned
+// if the road has no weight. The weight of a quoted name is the next token of the scanner
+func (p *parser) splitWeight(token string) (string, int, error) {
+	weight := ""
+	if strings.HasPrefix(token, `"`) {
+		if p.s.Peek() != roadWeightSign {
+			return token, 0, nil
+		}
+		p.s.Scan()
+		weight = p.s.TokenText()[1:]
+	} else {
+		i := strings.IndexRune(token, roadWeightSign)
+		if i == -1 {
+			return token, 0, nil
+		}
+		token, weight = token[:i], token[i+1:]
+	}
+	w, err := strconv.Atoi(weight)
+	if err != nil || w < 1 {
+		return token, 0, newParserError(p.s.Pos(), fmt.Sprintf("road weight must be a positive integer, got %s", weight))
+	}
+	return token, w, nil
+}
+
 // handleDirectionType handles direction type expectation
 func (p *parser) handleDirectionType(token string) error {
 	// validate mapDirection
-	if !isValidDirection(token) {
+	if !p.topology.Has(token) && p.schema.has(token) {
+		return p.handleAttributeName(token)
+	}
+	if !p.topology.Has(token) {
 		// unexpected mapDirection type
-		return newParserError(p.s.Pos(), fmt.Sprintf("got unexpected mapDirection type %s, expected one of south,north,west,east", token))
+		return newParserError(p.s.Pos(), fmt.Sprintf("got unexpected mapDirection type %s, expected one of %s", token, strings.Join(p.topology.directions, ",")))
 	}
 	// check for duplication
 	if p.parsedCities[p.currentCity].isDirectionSet(token) {
@@ -266,28 +544,57 @@ func (p *parser) handleDirectionType(token string) error {
 	return nil
 }
 
+// handleAttributeName handles an attribute name which goes instead of a direction type
+func (p *parser) handleAttributeName(token string) error {
+	if _, ok := p.parsedCities[p.currentCity].attributes[token]; ok {
+		return newParserError(p.s.Pos(), fmt.Sprintf("got attribute duplication %s for city %s", token, p.currentCity))
+	}
+	p.currentAttribute = token
+	p.currentExpectation = expectEqualSign
+	return nil
+}
+
+// handleAttributeValue handles attribute value expectation
+func (p *parser) handleAttributeValue(token string) error {
+	value, err := p.schema.parseValue(p.currentAttribute, token)
+	if err != nil {
+		return newParserError(p.s.Pos(), err.Error())
+	}
+	p.parsedCities[p.currentCity].attributes[p.currentAttribute] = value
+	p.parsedCities[p.currentCity].positions[p.currentAttribute] = p.s.Pos()
+	p.currentAttribute = ""
+	p.currentExpectation = expectDirectionType
+	return nil
+}
+
 // handleEqualSign handles equal sign expectation
 func (p *parser) handleEqualSign(token string) error {
 	if token != "=" {
 		return newParserError(p.s.Pos(), fmt.Sprintf("unexpected token %s, expected =", token))
 	}
+	if p.currentAttribute != "" {
+		p.currentExpectation = expectAttributeValue
+		return nil
+	}
 	p.currentExpectation = expectDirectionValue
 	return nil
 }
 
-// checkDirectionValuesExistence checks parsed input that it has no direction value pointed to nonexistent cities
+// checkDirectionValuesExistence checks parsed input that it has no direction value pointed to nonexistent cities.
+// All such directions are returned as MapErrors
 func (p *parser) checkDirectionValuesExistence() error {
 	if !p.parsed {
 		return errors.New("cannot check direction value for unparsed file")
 	}
+	var errs MapErrors
 	for n, c := range p.parsedCities {
 		for _, d := range c.getDirections() {
 			if _, ok := p.parsedCities[d.directionValue]; !ok {
-				return fmt.Errorf("city %s on line %d has direction %s which points to non existent city %s", n, c.line, d.directionType, d.directionValue)
+				errs = append(errs, newParserError(c.positions[d.directionType], fmt.Sprintf("city %s has direction %s which points to non existent city %s", n, d.directionType, d.directionValue)))
 			}
 		}
 	}
-	return nil
+	return errs.err()
 }
 
 // buildSimulation builds Simulation from parsed input
@@ -299,6 +606,12 @@ func (p *parser) buildSimulation() (*Simulation, error) {
 	// go through parsed cities
 	// fulfill simulation
 	s := &Simulation{initialMap: map[string]*city{}}
+	if p.topology != DefaultTopology() {
+		s.topology = p.topology
+	}
+	if len(p.schema.custom()) != 0 {
+		s.schema = p.schema
+	}
 	for name, pc := range p.parsedCities {
 
 		s.initialMap[name] = &city{
@@ -306,31 +619,111 @@ func (p *parser) buildSimulation() (*Simulation, error) {
 			isDestroyed: false,
 			directions:  pc.getDirections(),
 		}
+		if len(pc.attributes) != 0 {
+			s.initialMap[name].attributes = pc.attributes
+		}
 	}
 	return s, nil
 }
 
+// inferReverseRoads adds the opposite direction to every one-way road, so `Paris south=Boston` gets
+// `Boston north=Paris`. Roads to non existent cities and to the city itself are left as they are.
+// Cities are processed in the order of the input, roads which can't get a way back are returned as MapErrors
+func (p *parser) inferReverseRoads() error {
+	if !p.parsed {
+		return errors.New("cannot infer roads for unparsed file")
+	}
+	var errs MapErrors
+	for _, l := range p.layout {
+		if l.kind != layoutCity {
+			continue
+		}
+		name, pc := l.text, p.parsedCities[l.text]
+		for _, d := range pc.getDirections() {
+			target, ok := p.parsedCities[d.directionValue]
+			if !ok || d.directionValue == name {
+				continue
+			}
+			opposite := p.topology.Opposite(d.directionType)
+			if opposite == "" {
+				continue
+			}
+			switch value := target.getDirection(opposite); {
+			case value == name:
+				continue
+			case value != "":
+				errs = append(errs, newParserError(pc.positions[d.directionType], fmt.Sprintf("cannot add road back from %s to %s, direction %s of %s already leads to %s", d.directionValue, name, opposite, d.directionValue, value)))
+			case target.directionExists(name):
+				errs = append(errs, newParserError(pc.positions[d.directionType], fmt.Sprintf("cannot add road back from %s to %s, %s already leads to %s by another direction", d.directionValue, name, d.directionValue, name)))
+			default:
+				target.setDirection(opposite, name)
+				target.positions[opposite] = pc.positions[d.directionType]
+				if weight, ok := pc.weights[d.directionType]; ok {
+					target.weights[opposite] = weight
+				}
+			}
+		}
+	}
+	return errs.err()
+}
+
+// ParseOptions changes the way a map file is read
+type ParseOptions struct {
+	// InferReverseRoads adds missing opposite directions to one-way roads.
+	// A road which can't get a way back because the opposite direction is taken makes the map invalid
+	InferReverseRoads bool
+}
+
 // CreateSimulationFromPath crates simulation from a map file
 func CreateSimulationFromPath(path string) (*Simulation, error) {
-	mapFile, err := os.Open(path)
+	return CreateSimulationFromPathWithOptions(path, ParseOptions{})
+}
+
+// CreateSimulationFromPathWithOptions crates simulation from a map file in any supported format
+// read with the provided options. The format of the file is detected by DetectMapCodec
+func CreateSimulationFromPathWithOptions(path string, opts ParseOptions) (*Simulation, error) {
+	src, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	defer mapFile.Close()
-
-	return createSimulation(mapFile, filepath.Base(path))
+	return createSimulationFromSource(src, filepath.Base(path), opts)
 }
 
-// createSimulation creates simulation from input
+// createSimulation creates simulation from input. All problems of the input are returned together as MapErrors
 func createSimulation(src io.Reader, filename string) (*Simulation, error) {
+	return createSimulationWithOptions(src, filename, ParseOptions{})
+}
+
+// createSimulationWithOptions creates simulation from input read with the provided options
+func createSimulationWithOptions(src io.Reader, filename string, opts ParseOptions) (*Simulation, error) {
 	p := newParser(src, filename)
-	err := p.parse()
-	if err != nil {
+	if err := p.parseWithOptions(opts); err != nil {
 		return nil, err
 	}
-	err = p.checkDirectionValuesExistence()
+	return p.buildSimulation()
+}
+
+// parseWithOptions parses input, checks that all roads lead to existent cities and applies the options
+func (p *parser) parseWithOptions(opts ParseOptions) error {
+	errs := collectErrors(nil, p.parse())
+	errs = collectErrors(errs, p.checkDirectionValuesExistence())
+	if err := errs.err(); err != nil {
+		return err
+	}
+	if opts.InferReverseRoads {
+		return p.inferReverseRoads()
+	}
+	return nil
+}
+
+// collectErrors appends the error to errs, MapErrors are appended one by one
+func collectErrors(errs MapErrors, err error) MapErrors {
+	var mapErrs MapErrors
+	if errors.As(err, &mapErrs) {
+		return append(errs, mapErrs...)
+	}
 	if err != nil {
-		return nil, err
+		return append(errs, err)
 	}
-	return p.buildSimulation()
+	return errs
 }
@@ -1,9 +1,11 @@
 package simulator
 
 import (
+	"errors"
 	"github.com/stretchr/testify/require"
 	"strings"
 	"testing"
+	"text/scanner"
 )
 
 type errorCase struct{ input, expectedErrorText string }
@@ -54,6 +56,26 @@ London west=Bolton`,
 		input:             `London west 12`,
 		expectedErrorText: "testing:1:15: unexpected token 12, expected =",
 	},
+	{
+		input:             "# cities of England\n\nLondon # no directions\n",
+		expectedErrorText: "testing:4:1: unexpected newline, city must contain at least one direction",
+	},
+	{
+		input:             "London west # unfinished direction\nBoston east=London",
+		expectedErrorText: "testing:2:1: unexpected newline, direction must have a value",
+	},
+	{
+		input:             "London west=Boston # comment\n\n  # indented comment\nLondon east=Bolton",
+		expectedErrorText: "testing:4:7: got city duplication for London previously declared on line 1",
+	},
+	{
+		input:             "London west=Boston\n\n\nBoston nowhere=London # comment",
+		expectedErrorText: "testing:4:15: got unexpected mapDirection type nowhere, expected one of south,north,west,east",
+	},
+	{
+		input:             "London west=Boston #comment\nBolton west=#London",
+		expectedErrorText: "testing:2:20: Unexpected EOF",
+	},
 }
 
 func TestParserErrorHandling(t *testing.T) {
@@ -67,7 +89,7 @@ func TestParserErrorHandling(t *testing.T) {
 func TestHandleNonExistentDirectionValue(t *testing.T) {
 	input := `London west=Boston east=Bolton
 Bolton west=London `
-	expectedError := "city London on line 1 has direction west which points to non existent city Boston"
+	expectedError := "testing:1:19: city London has direction west which points to non existent city Boston"
 	prsr := newParser(strings.NewReader(input), "testing")
 	err := prsr.parse()
 	require.Nil(t, err)
@@ -119,3 +141,260 @@ Bolton west=London `
 	require.Nil(t, err)
 	require.EqualValues(t, expected, simulation)
 }
+
+func TestParserSkipsCommentsAndBlankLines(t *testing.T) {
+	input := `# Map of the test planet
+
+London east=Bolton # trailing comment with = signs, "quotes" and 👾
+   # indented comment
+Bolton west=London#comment right after a value
+
+# trailing comment at the end of file`
+	prsr := newParser(strings.NewReader(input), "testing")
+	err := prsr.parse()
+	require.Nil(t, err)
+	require.Len(t, prsr.parsedCities, 2)
+	require.Equal(t, 3, prsr.parsedCities["London"].line)
+	require.Equal(t, "Bolton", prsr.parsedCities["London"].getDirection(directionEast))
+	require.Equal(t, 5, prsr.parsedCities["Bolton"].line)
+	require.Equal(t, "London", prsr.parsedCities["Bolton"].getDirection(directionWest))
+}
+
+func TestParserAcceptsEmptyInput(t *testing.T) {
+	for _, input := range []string{"", "\n\n", "# only a comment", "# comment\n\n# comment\n"} {
+		prsr := newParser(strings.NewReader(input), "testing")
+		require.Nil(t, prsr.parse(), input)
+		require.Empty(t, prsr.parsedCities, input)
+	}
+}
+
+func TestParserCollectsAllErrors(t *testing.T) {
+	input := `London west=Boston* east=Bolton
+Bolton west=London north=Paris
+Paris nowhere=London south=Bolton
+Paris east=Bolton
+Rome
+Madrid north=Bolton south=Atlantis west=Lisbon`
+	_, err := createSimulation(strings.NewReader(input), "testing")
+	require.EqualError(t, err, `testing:1:20: expected a valid city name as a mapDirection value, got Boston*
+testing:3:14: got unexpected mapDirection type nowhere, expected one of south,north,west,east
+testing:4:6: got city duplication for Paris previously declared on line 3
+testing:6:1: unexpected newline, city must contain at least one direction
+testing:6:35: city Madrid has direction south which points to non existent city Atlantis
+testing:6:47: city Madrid has direction west which points to non existent city Lisbon`)
+
+	var mapErrs MapErrors
+	require.True(t, errors.As(err, &mapErrs))
+	require.Len(t, mapErrs, 6)
+	for _, e := range mapErrs {
+		require.IsType(t, parserError{}, e)
+	}
+}
+
+func TestMapErrorsAreSortedByPosition(t *testing.T) {
+	errs := MapErrors{
+		newParserError(scanner.Position{Line: 3, Column: 1}, "third"),
+		newParserError(scanner.Position{Line: 1, Column: 9}, "second"),
+		errors.New("no position"),
+		newParserError(scanner.Position{Line: 1, Column: 2}, "first"),
+	}
+	require.EqualError(t, errs.err(), "no position\n:1:2: first\n:1:9: second\n:3:1: third")
+	require.Nil(t, MapErrors{}.err())
+}
+
+func TestInferReverseRoadsAddsOppositeDirections(t *testing.T) {
+	input := `Paris south=Boston east=Paris
+Boston west=Rome
+Rome east=Boston
+`
+	s, err := createSimulationWithOptions(strings.NewReader(input), "testing", ParseOptions{InferReverseRoads: true})
+	require.Nil(t, err)
+	require.Equal(t, []mapDirection{
+		{directionType: directionNorth, directionValue: "Paris"},
+		{directionType: directionWest, directionValue: "Rome"},
+	}, s.initialMap["Boston"].directions)
+	// a road to itself has no way back to add
+	require.Equal(t, []mapDirection{
+		{directionType: directionSouth, directionValue: "Boston"},
+		{directionType: directionEast, directionValue: "Paris"},
+	}, s.initialMap["Paris"].directions)
+}
+
+func TestInferReverseRoadsReportsConflicts(t *testing.T) {
+	input := `Paris south=Boston
+Rome south=Boston
+Madrid east=Boston
+Boston north=Rome east=Madrid
+`
+	_, err := createSimulationWithOptions(strings.NewReader(input), "testing", ParseOptions{InferReverseRoads: true})
+	require.EqualError(t, err, strings.Join([]string{
+		"testing:1:19: cannot add road back from Boston to Paris, direction north of Boston already leads to Rome",
+		"testing:3:19: cannot add road back from Boston to Madrid, Boston already leads to Madrid by another direction",
+		"testing:4:30: cannot add road back from Madrid to Boston, Madrid already leads to Boston by another direction",
+	}, "\n"))
+}
+
+func TestOneWayRoadsAreKeptByDefault(t *testing.T) {
+	s, err := createSimulation(strings.NewReader("Paris south=Boston\nBoston west=Rome\nRome east=Boston\n"), "testing")
+	require.Nil(t, err)
+	require.Equal(t, []mapDirection{{directionType: directionWest, directionValue: "Rome"}}, s.initialMap["Boston"].directions)
+}
+
+func TestParserReadsTopologyDeclaration(t *testing.T) {
+	input := `# hex map
+@topology hex # six neighbours
+Paris northeast=Berlin west=Madrid
+Berlin southwest=Paris
+Madrid east=Paris
+`
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	require.Equal(t, TopologyHex, s.Topology().Name())
+	require.Equal(t, []mapDirection{
+		{directionType: "northeast", directionValue: "Berlin"},
+		{directionType: directionWest, directionValue: "Madrid"},
+	}, s.initialMap["Paris"].directions)
+}
+
+func TestParserUsesDefaultTopologyWithoutDeclaration(t *testing.T) {
+	s, err := createSimulation(strings.NewReader("Paris north=Berlin\nBerlin south=Paris\n"), "testing")
+	require.Nil(t, err)
+	require.Equal(t, DefaultTopology(), s.Topology())
+}
+
+func TestParserFailsOnInvalidTopologyDeclaration(t *testing.T) {
+	testCases := []struct {
+		input string
+		err   string
+	}{
+		{input: "@topology square\nParis north=Paris", err: "testing:1:10: unknown topology square, expected one of compass,octagonal,hex or a list of directions"},
+		{input: "@layout hex\nParis north=Paris", err: "testing:1:8: unknown directive @layout, expected one of @topology,@attribute"},
+		{input: "@topology hex\n@topology hex\nParis east=Paris", err: "testing:2:10: got topology duplication, topology is already declared on line 1"},
+		{input: "Paris north=Paris\n@topology hex", err: "testing:2:10: topology must be declared before the first city"},
+		{input: "@topology up:down\nParis north=Paris", err: "testing:2:12: got unexpected mapDirection type north, expected one of up,down"},
+	}
+	for _, tc := range testCases {
+		_, err := createSimulation(strings.NewReader(tc.input), "testing")
+		require.EqualError(t, err, tc.err, tc.input)
+	}
+}
+
+func TestInferReverseRoadsSkipsDirectionsWithoutOpposite(t *testing.T) {
+	input := "@topology up:down portal\nLab portal=Moon\nMoon down=Base\nBase portal=Lab\n"
+	s, err := createSimulationWithOptions(strings.NewReader(input), "testing", ParseOptions{InferReverseRoads: true})
+	require.Nil(t, err)
+	require.Equal(t, []mapDirection{{directionType: "down", directionValue: "Base"}}, s.initialMap["Moon"].directions)
+	require.Equal(t, []mapDirection{
+		{directionType: "up", directionValue: "Moon"},
+		{directionType: "portal", directionValue: "Lab"},
+	}, s.initialMap["Base"].directions)
+}
+
+func TestParserAcceptsUnicodeAndQuotedCityNames(t *testing.T) {
+	input := `São_Paulo east=Zürich north="New York"
+Zürich west=São_Paulo south=Москва
+Москва north=Zürich west=City10
+City10 east=Москва
+"New York" south=São_Paulo
+`
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	require.Equal(t, []string{"City10", "New York", "São_Paulo", "Zürich", "Москва"}, s.initialMap.sortedNames())
+	require.Equal(t, []mapDirection{
+		{directionType: directionNorth, directionValue: "New York"},
+		{directionType: directionEast, directionValue: "Zürich"},
+	}, s.initialMap["São_Paulo"].directions)
+}
+
+func TestParserNormalizesCityNames(t *testing.T) {
+	// the same name is written with a precomposed ü and with u followed by a combining diaeresis
+	input := "Z\u00fcrich east=Bern\nBern west=Zu\u0308rich\nZu\u0308rich east=Bern\n"
+	_, err := createSimulation(strings.NewReader(input), "testing")
+	require.EqualError(t, err, "testing:3:8: got city duplication for Z\u00fcrich previously declared on line 1")
+
+	s, err := createSimulation(strings.NewReader("Zu\u0308rich east=Bern\nBern west=Z\u00fcrich\n"), "testing")
+	require.Nil(t, err)
+	require.Equal(t, "Z\u00fcrich", s.initialMap["Bern"].directions[0].directionValue)
+	require.Contains(t, s.initialMap, "Z\u00fcrich")
+}
+
+func TestParserFailsOnInvalidQuotedCityNames(t *testing.T) {
+	testCases := []struct {
+		input string
+		err   string
+	}{
+		{input: `"New  York" south=Boston`, err: `testing:1:12: expected a valid city name, got "New  York"`},
+		{input: `" Boston" south=Boston`, err: `testing:1:10: expected a valid city name, got " Boston"`},
+		{input: `Boston north="New York*"`, err: `testing:1:25: expected a valid city name as a mapDirection value, got "New York*"`},
+		{input: "Boston north=\"New York\nNew_York south=Boston", err: "testing:1:14: literal not terminated"},
+	}
+	for _, tc := range testCases {
+		_, err := createSimulation(strings.NewReader(tc.input), "testing")
+		require.EqualError(t, err, tc.err, tc.input)
+	}
+}
+
+func TestParserReadsCityAttributes(t *testing.T) {
+	input := `@attribute airport bool
+@attribute country string
+Paris population=2100000 defense=3 north=Berlin airport=true country=France
+Berlin south=Paris capacity=2
+`
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	require.Equal(t, Attributes{
+		"population": int64(2100000),
+		"defense":    int64(3),
+		"airport":    true,
+		"country":    "France",
+	}, s.initialMap["Paris"].attributes)
+	require.Equal(t, Attributes{"capacity": int64(2)}, s.initialMap["Berlin"].attributes)
+	require.Equal(t, []mapDirection{{directionType: directionNorth, directionValue: "Berlin"}}, s.initialMap["Paris"].directions)
+}
+
+func TestParserFailsOnInvalidAttributes(t *testing.T) {
+	testCases := []struct {
+		input string
+		err   string
+	}{
+		{input: "Paris population=many north=Paris", err: "testing:1:22: invalid value many of attribute population, expected an integer"},
+		{input: "Paris defense=1 defense=2 north=Paris", err: "testing:1:24: got attribute duplication defense for city Paris"},
+		{input: "Paris airport=true north=Paris", err: "testing:1:14: got unexpected mapDirection type airport, expected one of south,north,west,east"},
+		{input: "Paris population=\nBerlin north=Berlin", err: "testing:2:1: unexpected newline, direction must have a value"},
+		{input: "@attribute airport\nParis north=Paris", err: "testing:1:11: expected @attribute name type, got @attribute airport"},
+		{input: "@attribute north int\nParis north=Paris", err: "testing:1:11: attribute north conflicts with direction north"},
+		{input: "@attribute up int\n@topology up:down\nParis north=Paris", err: "testing:2:10: direction up conflicts with attribute up"},
+		{input: "Paris north=Paris\n@attribute airport bool", err: "testing:2:11: attribute must be declared before the first city"},
+	}
+	for _, tc := range testCases {
+		_, err := createSimulation(strings.NewReader(tc.input), "testing")
+		require.EqualError(t, err, tc.err, tc.input)
+	}
+}
+
+func TestParserReadsRoadWeights(t *testing.T) {
+	input := "Boston east=Vladivostok:4 north=\"New York\":2\nVladivostok west=Boston:4\n\"New York\" south=Boston:1\n"
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	require.Equal(t, []mapDirection{
+		{directionType: directionNorth, directionValue: "New York", transit: 1},
+		{directionType: directionEast, directionValue: "Vladivostok", transit: 3},
+	}, s.initialMap["Boston"].directions)
+	require.Equal(t, []mapDirection{{directionType: directionSouth, directionValue: "Boston"}}, s.initialMap["New York"].directions)
+
+	for input, expected := range map[string]string{
+		"Boston east=Boston:0":    "testing:1:21: road weight must be a positive integer, got 0",
+		"Boston east=Boston:far":  "testing:1:23: road weight must be a positive integer, got far",
+		"Boston east=\"Boston\":": "testing:1:22: road weight must be a positive integer, got ",
+	} {
+		_, err := createSimulation(strings.NewReader(input), "testing")
+		require.EqualError(t, err, expected, input)
+	}
+}
+
+func TestInferReverseRoadsKeepsWeights(t *testing.T) {
+	input := "Boston east=Vladivostok:4\nVladivostok north=Lima\nLima south=Vladivostok\n"
+	s, err := createSimulationWithOptions(strings.NewReader(input), "testing", ParseOptions{InferReverseRoads: true})
+	require.Nil(t, err)
+	require.Equal(t, mapDirection{directionType: directionWest, directionValue: "Boston", transit: 3}, s.initialMap["Vladivostok"].directions[1])
+}
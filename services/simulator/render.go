@@ -0,0 +1,85 @@
+package simulator
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// RenderEvent returns a human-readable message about the event. Returns false for events
+// which are too frequent to be rendered, like spawns and moves of aliens
+func RenderEvent(e Event) (string, bool) {
+	switch e := e.(type) {
+	case SimulationStarted:
+		return fmt.Sprintf("Simulate invasion with %d aliens", e.Aliens), true
+	case BattleOccurred:
+		return battleMessage(e.City, e.Aliens), true
+	case AlienTrapped:
+		return fmt.Sprintf("Alien 👾%d is trapped in the city of %s. All roads lead to ruins.", e.Alien, e.City), true
+	case SimulationEnded:
+		switch e.Reason {
+		case EndReasonAllDead:
+			return fmt.Sprintf("All aliens are dead, simulations is over on turn number %d", e.Turn), true
+		case EndReasonLocked:
+			return fmt.Sprintf("All aliens are either dead or locked, simulations is over on turn number %d", e.Turn), true
+		default:
+			return fmt.Sprintf("%d turns are finished. Simulation is over", e.Turn+1), true
+		}
+	default:
+		return "", false
+	}
+}
+
+// battleMessage returns a battle message based on the city name and aliens in it
+func battleMessage(cityName string, aliens []int64) string {
+	builder := strings.Builder{}
+	builder.WriteString("Aliens: ")
+	for i, a := range aliens {
+		builder.WriteString("👾")
+		builder.WriteString(strconv.Itoa(int(a)))
+		if i != len(aliens)-1 {
+			builder.WriteString(", ")
+		}
+	}
+	builder.WriteString(fmt.Sprintf(" have met in the city of %s. ⚔ Battle destroyed the city.", cityName))
+
+	return builder.String()
+}
+
+// RenderEvents returns human-readable messages about the events
+func RenderEvents(events []Event) []string {
+	var logs []string
+	for _, e := range events {
+		if msg, ok := RenderEvent(e); ok {
+			logs = append(logs, msg)
+		}
+	}
+	return logs
+}
+
+// TextRenderer is an EventSink which writes human-readable messages about events line by line
+type TextRenderer struct {
+	out io.Writer
+	err error
+}
+
+// NewTextRenderer creates new text renderer which writes messages to out
+func NewTextRenderer(out io.Writer) *TextRenderer {
+	return &TextRenderer{out: out}
+}
+
+// Emit writes a message about the event. After the first write error all events are ignored
+func (r *TextRenderer) Emit(e Event) {
+	if r.err != nil {
+		return
+	}
+	if msg, ok := RenderEvent(e); ok {
+		_, r.err = fmt.Fprintln(r.out, msg)
+	}
+}
+
+// Err returns the first write error occurred during rendering
+func (r *TextRenderer) Err() error {
+	return r.err
+}
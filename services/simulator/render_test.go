@@ -0,0 +1,57 @@
+package simulator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderEvent(t *testing.T) {
+	cases := []struct {
+		event    Event
+		expected string
+	}{
+		{event: SimulationStarted{Aliens: 15}, expected: "Simulate invasion with 15 aliens"},
+		{event: BattleOccurred{City: "Boston", Aliens: []int64{11, 2, 5}}, expected: "Aliens: 👾11, 👾2, 👾5 have met in the city of Boston. ⚔ Battle destroyed the city."},
+		{event: AlienTrapped{Alien: 3, City: "Porto"}, expected: "Alien 👾3 is trapped in the city of Porto. All roads lead to ruins."},
+		{event: SimulationEnded{Turn: 12, Reason: EndReasonAllDead}, expected: "All aliens are dead, simulations is over on turn number 12"},
+		{event: SimulationEnded{Turn: 4, Reason: EndReasonLocked}, expected: "All aliens are either dead or locked, simulations is over on turn number 4"},
+		{event: SimulationEnded{Turn: 9999, Reason: EndReasonMaxTurns}, expected: "10000 turns are finished. Simulation is over"},
+	}
+	for _, tc := range cases {
+		msg, ok := RenderEvent(tc.event)
+		require.True(t, ok, tc.event.EventName())
+		require.Equal(t, tc.expected, msg)
+	}
+
+	for _, e := range []Event{AlienSpawned{}, AlienMoved{}, CityDestroyed{}} {
+		_, ok := RenderEvent(e)
+		require.False(t, ok, e.EventName())
+	}
+}
+
+func TestTextRendererWritesLines(t *testing.T) {
+	builder := strings.Builder{}
+	r := NewTextRenderer(&builder)
+	r.Emit(SimulationStarted{Aliens: 2})
+	r.Emit(AlienSpawned{Alien: 0, City: "Paris"})
+	r.Emit(SimulationEnded{Turn: 0, Reason: EndReasonAllDead})
+	require.Nil(t, r.Err())
+	require.Equal(t, "Simulate invasion with 2 aliens\nAll aliens are dead, simulations is over on turn number 0\n", builder.String())
+}
+
+// failingWriter fails on every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk is full")
+}
+
+func TestTextRendererKeepsFirstError(t *testing.T) {
+	r := NewTextRenderer(failingWriter{})
+	r.Emit(SimulationStarted{Aliens: 2})
+	r.Emit(SimulationEnded{Turn: 0, Reason: EndReasonAllDead})
+	require.EqualError(t, r.Err(), "disk is full")
+}
@@ -0,0 +1,403 @@
+package simulator
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ivanovpetr/invasion/services/layout"
+)
+
+// Size of a grid cell and a city in the SVG map of a report in pixels
+const (
+	reportCellWidth  = 150
+	reportCellHeight = 90
+	reportCityWidth  = 120
+	reportCityHeight = 36
+	reportMargin     = 30
+)
+
+// ReportDetail is a row of the summary table of a report
+type ReportDetail struct {
+	Name  string
+	Value string
+}
+
+// ReportOptions describes a simulation in an HTML report with details the result doesn't know
+type ReportOptions struct {
+	// Title of the report, for example the name of the map file
+	Title string
+	// Details are added to the summary table before details of the result, for example the seed
+	Details []ReportDetail
+}
+
+// reportCity is a city of the SVG map
+type reportCity struct {
+	Name string  `json:"name"`
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+}
+
+// reportRoad is a road of the SVG map, a pair of roads between the same cities is drawn once
+type reportRoad struct {
+	X1, Y1, X2, Y2 float64
+	Label          string
+}
+
+// reportAlien is the position of an alien in a frame, an alien on a long road has the city it came from
+type reportAlien struct {
+	ID      int64  `json:"id"`
+	City    string `json:"city"`
+	From    string `json:"from,omitempty"`
+	Transit int    `json:"transit,omitempty"`
+	Trapped bool   `json:"trapped,omitempty"`
+}
+
+// reportFrame is the state of the map after a turn, the first frame is the state right after landing
+type reportFrame struct {
+	Turn      int           `json:"turn"`
+	Aliens    []reportAlien `json:"aliens"`
+	Destroyed []string      `json:"destroyed"`
+}
+
+// reportBattle is an entry of the battle timeline
+type reportBattle struct {
+	Turn   int
+	Frame  int
+	City   string
+	Aliens string
+}
+
+// reportData is everything the report template needs
+type reportData struct {
+	Title string
+	// index of the last frame, the maximum of the turn slider
+	LastFrame int
+	Width     int
+	Height    int
+	Cities    []reportCity
+	Roads     []reportRoad
+	Frames    []reportFrame
+	Battles   []reportBattle
+	Details   []ReportDetail
+}
+
+// WriteHTMLReport writes a self-contained HTML report of the simulation: an SVG map laid out from directions
+// of roads, a turn slider which replays positions of aliens, a timeline of battles and a summary table.
+// The report is built from events of the simulation and doesn't need any network resources
+func (sr *SimulationResult) WriteHTMLReport(out io.Writer, opts ReportOptions) error {
+	data := reportData{
+		Title:   opts.Title,
+		Frames:  reportFrames(sr.Events, sr.Turns),
+		Details: append(append([]ReportDetail{}, opts.Details...), sr.reportDetails()...),
+	}
+	data.LastFrame = len(data.Frames) - 1
+	if data.Title == "" {
+		data.Title = "Invasion report"
+	}
+	data.Cities, data.Roads, data.Width, data.Height = reportMap(sr.ResultMap, mapLayout(sr.ResultMap, sr.topology).Positions)
+	for _, e := range sr.Events {
+		if e, ok := e.(BattleOccurred); ok {
+			data.Battles = append(data.Battles, reportBattle{Turn: e.Turn, Frame: e.Turn + 1, City: e.City, Aliens: joinIDs(e.Aliens)})
+		}
+	}
+	if err := reportTemplate.Execute(out, data); err != nil {
+		return fmt.Errorf("failed to write HTML report: %w", err)
+	}
+	return nil
+}
+
+// reportDetails returns rows of the summary table which describe the result
+func (sr *SimulationResult) reportDetails() []ReportDetail {
+	alive := 0
+	for _, a := range sr.Aliens {
+		if !a.isDead {
+			alive++
+		}
+	}
+	destroyed := sr.DestroyedCities()
+	details := []ReportDetail{
+		{Name: "Aliens", Value: strconv.Itoa(len(sr.Aliens))},
+		{Name: "Surviving aliens", Value: strconv.Itoa(alive)},
+		{Name: "Turns", Value: strconv.Itoa(sr.Turns)},
+		{Name: "End reason", Value: string(sr.EndReason)},
+		{Name: "Cities", Value: strconv.Itoa(len(sr.ResultMap))},
+		{Name: "Destroyed cities", Value: fmt.Sprintf("%d %s", len(destroyed), strings.Join(destroyed, ", "))},
+	}
+	if population := sr.DestroyedPopulation(); population != 0 {
+		details = append(details, ReportDetail{Name: "Destroyed population", Value: strconv.FormatInt(population, 10)})
+	}
+	return details
+}
+
+// reportMap returns centers of cities and roads of the SVG map with its size
+func reportMap(cities planetMap, positions map[string]layout.Point) ([]reportCity, []reportRoad, int, int) {
+	center := func(name string) (float64, float64) {
+		p := positions[name]
+		return float64(reportMargin + p.X*reportCellWidth + reportCityWidth/2), float64(reportMargin + p.Y*reportCellHeight + reportCityHeight/2)
+	}
+	width, height := 0, 0
+	var reportCities []reportCity
+	var roads []reportRoad
+	drawn := map[[2]string]int{}
+	for _, name := range cities.sortedNames() {
+		x, y := center(name)
+		reportCities = append(reportCities, reportCity{Name: name, X: x, Y: y})
+		if w := int(x) + reportCityWidth/2 + reportMargin; w > width {
+			width = w
+		}
+		if h := int(y) + reportCityHeight/2 + reportMargin; h > height {
+			height = h
+		}
+		for _, d := range cities[name].directions {
+			if _, ok := cities[d.directionValue]; !ok || d.directionValue == name {
+				continue
+			}
+			label := d.directionType
+			if d.transit > 0 {
+				label += fmt.Sprintf(" (%d turns)", d.road().Turns())
+			}
+			// the way back shares the line with the road
+			pair := [2]string{name, d.directionValue}
+			if pair[0] > pair[1] {
+				pair[0], pair[1] = pair[1], pair[0]
+			}
+			if i, ok := drawn[pair]; ok {
+				roads[i].Label += " / " + label
+				continue
+			}
+			x2, y2 := center(d.directionValue)
+			drawn[pair] = len(roads)
+			roads = append(roads, reportRoad{X1: x, Y1: y, X2: x2, Y2: y2, Label: label})
+		}
+	}
+	return reportCities, roads, width, height
+}
+
+// reportFrames replays events and returns the state of the map after landing and after every turn
+func reportFrames(events []Event, turns int) []reportFrame {
+	aliens := map[int64]*reportAlien{}
+	destroyed := map[string]bool{}
+	frames := make([]reportFrame, 0, turns+1)
+	snapshot := func() {
+		frame := reportFrame{Turn: len(frames) - 1, Aliens: make([]reportAlien, 0, len(aliens)), Destroyed: make([]string, 0, len(destroyed))}
+		for _, a := range aliens {
+			frame.Aliens = append(frame.Aliens, *a)
+		}
+		sort.Slice(frame.Aliens, func(i, j int) bool { return frame.Aliens[i].ID < frame.Aliens[j].ID })
+		for name := range destroyed {
+			frame.Destroyed = append(frame.Destroyed, name)
+		}
+		sort.Strings(frame.Destroyed)
+		frames = append(frames, frame)
+		// aliens on long roads get one turn closer to their cities, the last turn is over when they arrive,
+		// because an alien waits on the road while its city is full
+		for _, a := range aliens {
+			if a.Transit > 1 {
+				a.Transit--
+			}
+		}
+	}
+
+	for _, e := range events {
+		switch e := e.(type) {
+		case SimulationStarted:
+			continue
+		case AlienSpawned:
+			aliens[e.Alien] = &reportAlien{ID: e.Alien, City: e.City}
+			continue
+		}
+		// the first frame is the landing, events of a turn go to the frame of the next index
+		for len(frames) < e.EventTurn()+1 {
+			snapshot()
+		}
+		switch e := e.(type) {
+		case AlienMoved:
+			a := aliens[e.Alien]
+			a.City, a.From, a.Transit = e.To, "", e.Transit
+			if e.Transit > 0 {
+				a.From = e.From
+			}
+		case AlienArrived:
+			a := aliens[e.Alien]
+			a.From, a.Transit = "", 0
+		case AlienTrapped:
+			// an alien on the road is trapped when its city is destroyed before it arrives
+			a := aliens[e.Alien]
+			a.From, a.Transit, a.Trapped = "", 0, true
+		case CityDestroyed:
+			destroyed[e.City] = true
+			for _, id := range e.Aliens {
+				delete(aliens, id)
+			}
+		}
+	}
+	for len(frames) < turns+1 {
+		snapshot()
+	}
+	return frames
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; margin: 24px; color: #222; }
+h1 { font-size: 22px; }
+h2 { font-size: 17px; margin-top: 28px; }
+.map { border: 1px solid #ddd; background: #fafafa; max-width: 100%; overflow: auto; }
+.road { stroke: #999; stroke-width: 1.5; }
+.road-label { font-size: 10px; fill: #777; text-anchor: middle; }
+.city rect { fill: #fff; stroke: #555; stroke-width: 1.5; }
+.city text { font-size: 12px; text-anchor: middle; dominant-baseline: central; }
+.city.destroyed rect { fill: #f4cccc; stroke: #cc0000; }
+.city.destroyed text { fill: #cc0000; text-decoration: line-through; }
+.alien { fill: #1155cc; }
+.alien.trapped { fill: #e69138; }
+.alien.transit { fill: #ffffff; stroke: #1155cc; stroke-width: 2; }
+.controls { margin: 12px 0; }
+.controls input { width: 420px; vertical-align: middle; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ddd; padding: 4px 10px; text-align: left; }
+.timeline button { border: none; background: none; color: #1155cc; cursor: pointer; padding: 0; font: inherit; }
+.timeline li.current { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+
+<h2>Map</h2>
+<div class="controls">
+<input type="range" id="turn" min="0" max="{{.LastFrame}}" value="0" step="1">
+<span id="turn-label"></span>
+</div>
+<svg class="map" width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}" xmlns="http://www.w3.org/2000/svg">
+<g id="roads">
+{{- range .Roads}}
+<line class="road" x1="{{.X1}}" y1="{{.Y1}}" x2="{{.X2}}" y2="{{.Y2}}"></line>
+<text class="road-label" x="{{.LabelX}}" y="{{.LabelY}}">{{.Label}}</text>
+{{- end}}
+</g>
+<g id="cities">
+{{- range $i, $c := .Cities}}
+<g class="city" id="city-{{$i}}"><rect x="{{.Left}}" y="{{.Top}}" width="{{.Width}}" height="{{.Height}}" rx="8"></rect><text x="{{.X}}" y="{{.Y}}">{{.Name}}</text></g>
+{{- end}}
+</g>
+<g id="aliens"></g>
+</svg>
+
+<h2>Battles</h2>
+{{- if .Battles}}
+<ol class="timeline">
+{{- range .Battles}}
+<li data-frame="{{.Frame}}"><button type="button" data-frame="{{.Frame}}">Turn {{.Turn}}</button>: aliens {{.Aliens}} destroyed {{.City}}</li>
+{{- end}}
+</ol>
+{{- else}}
+<p>No battles happened.</p>
+{{- end}}
+
+<h2>Summary</h2>
+<table>
+{{- range .Details}}
+<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+
+<script>
+const cities = {{.Cities}};
+const frames = {{.Frames}};
+(function () {
+  const svgNS = "http://www.w3.org/2000/svg";
+  const index = {};
+  cities.forEach(function (c, i) { index[c.name] = i; });
+  const slider = document.getElementById("turn");
+  const label = document.getElementById("turn-label");
+  const layer = document.getElementById("aliens");
+
+  function show(n) {
+    const frame = frames[n];
+    slider.value = n;
+    label.textContent = n === 0 ? "Landing" : "After turn " + frame.turn;
+    const destroyed = {};
+    frame.destroyed.forEach(function (name) { destroyed[name] = true; });
+    cities.forEach(function (c, i) {
+      document.getElementById("city-" + i).classList.toggle("destroyed", !!destroyed[c.name]);
+    });
+    while (layer.firstChild) {
+      layer.removeChild(layer.firstChild);
+    }
+    const seats = {};
+    frame.aliens.forEach(function (a) {
+      const to = cities[index[a.city]];
+      let x = to.x, y = to.y - 18, cls = "alien";
+      if (a.from) {
+        const from = cities[index[a.from]];
+        x = (from.x + to.x) / 2;
+        y = (from.y + to.y) / 2;
+        cls += " transit";
+      } else if (a.trapped) {
+        cls += " trapped";
+      }
+      const seat = seats[x + ":" + y] || 0;
+      seats[x + ":" + y] = seat + 1;
+      const dot = document.createElementNS(svgNS, "circle");
+      dot.setAttribute("cx", x - 50 + (seat % 10) * 11);
+      dot.setAttribute("cy", y - Math.floor(seat / 10) * 11);
+      dot.setAttribute("r", 5);
+      dot.setAttribute("class", cls);
+      const title = document.createElementNS(svgNS, "title");
+      title.textContent = "Alien " + a.id + (a.from ? " on the way to " + a.city + ", " + a.transit + " turns left" : " in " + a.city);
+      dot.appendChild(title);
+      layer.appendChild(dot);
+    });
+    document.querySelectorAll(".timeline li").forEach(function (li) {
+      li.classList.toggle("current", Number(li.dataset.frame) === n);
+    });
+  }
+
+  slider.addEventListener("input", function () { show(Number(slider.value)); });
+  document.querySelectorAll(".timeline button").forEach(function (b) {
+    b.addEventListener("click", function () { show(Number(b.dataset.frame)); });
+  });
+  show(0);
+})();
+</script>
+</body>
+</html>
+`))
+
+// LabelX returns the horizontal position of the road label
+func (r reportRoad) LabelX() float64 {
+	return (r.X1 + r.X2) / 2
+}
+
+// LabelY returns the vertical position of the road label, it is slightly above the road
+func (r reportRoad) LabelY() float64 {
+	return (r.Y1+r.Y2)/2 - 4
+}
+
+// Left returns the left edge of the city box
+func (c reportCity) Left() float64 {
+	return c.X - reportCityWidth/2
+}
+
+// Top returns the top edge of the city box
+func (c reportCity) Top() float64 {
+	return c.Y - reportCityHeight/2
+}
+
+// Width returns the width of the city box
+func (c reportCity) Width() int {
+	return reportCityWidth
+}
+
+// Height returns the height of the city box
+func (c reportCity) Height() int {
+	return reportCityHeight
+}
@@ -0,0 +1,57 @@
+package simulator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportFramesReplayEvents(t *testing.T) {
+	events := []Event{
+		SimulationStarted{Turn: 0, Aliens: 3},
+		AlienSpawned{Turn: 0, Alien: 0, City: "Madrid"},
+		AlienSpawned{Turn: 0, Alien: 1, City: "Madrid"},
+		AlienSpawned{Turn: 0, Alien: 2, City: "Boston"},
+		BattleOccurred{Turn: 0, City: "Madrid", Aliens: []int64{0, 1}},
+		CityDestroyed{Turn: 0, City: "Madrid", Aliens: []int64{0, 1}},
+		AlienMoved{Turn: 0, Alien: 2, From: "Boston", To: "Porto", Direction: "east", Transit: 1},
+		AlienArrived{Turn: 1, Alien: 2, City: "Porto"},
+		AlienTrapped{Turn: 2, Alien: 2, City: "Porto"},
+		SimulationEnded{Turn: 2, Reason: EndReasonLocked},
+	}
+	require.Equal(t, []reportFrame{
+		{Turn: -1, Aliens: []reportAlien{{ID: 0, City: "Madrid"}, {ID: 1, City: "Madrid"}, {ID: 2, City: "Boston"}}, Destroyed: []string{}},
+		{Turn: 0, Aliens: []reportAlien{{ID: 2, City: "Porto", From: "Boston", Transit: 1}}, Destroyed: []string{"Madrid"}},
+		{Turn: 1, Aliens: []reportAlien{{ID: 2, City: "Porto"}}, Destroyed: []string{"Madrid"}},
+		{Turn: 2, Aliens: []reportAlien{{ID: 2, City: "Porto", Trapped: true}}, Destroyed: []string{"Madrid"}},
+	}, reportFrames(events, 3))
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	input := `Boston east=Porto
+Porto east=Madrid
+Madrid west=Porto
+Lisbon north=Madrid`
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	s.SetSeed(1)
+	cfg := DefaultSimulationConfig(3)
+	cfg.Spawn = ListSpawn{Cities: []string{"Madrid", "Madrid", "Boston"}}
+	res, err := s.Run(cfg)
+	require.Nil(t, err)
+
+	buf := bytes.Buffer{}
+	require.Nil(t, res.WriteHTMLReport(&buf, ReportOptions{Title: "Invasion of <testing>", Details: []ReportDetail{{Name: "Seed", Value: "1"}}}))
+	report := buf.String()
+	require.Contains(t, report, "<title>Invasion of &lt;testing&gt;</title>")
+	require.Contains(t, report, `<li data-frame="1"><button type="button" data-frame="1">Turn 0</button>: aliens 0, 1 destroyed Madrid</li>`)
+	require.Contains(t, report, "<tr><th>Seed</th><td>1</td></tr>")
+	require.Contains(t, report, "<tr><th>Destroyed cities</th><td>1 Madrid</td></tr>")
+	require.Contains(t, report, `<text class="road-label" x="315" y="44">west / east</text>`)
+	require.Contains(t, report, `const frames = [{"turn":-1,`)
+	// the report works offline
+	require.NotContains(t, report, "<link")
+	require.NotContains(t, report, "src=")
+}
@@ -0,0 +1,244 @@
+package simulator
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Run is a single simulation which is played turn by turn
+type Run struct {
+	cfg    SimulationConfig
+	rnd    *rand.Rand
+	cities planetMap
+	names  []string
+	view   *mapView
+	aliens []alien
+	events []Event
+
+	// topology of the map, nil for the default one
+	topology *Topology
+	// attributes of cities, nil if the map declares no custom attributes
+	schema *attributeSchema
+
+	// number of played turns
+	turn int
+	// specifies either the simulation is over or not
+	done  bool
+	ended SimulationEnded
+}
+
+// CityState is a snapshot of a city during a simulation
+type CityState struct {
+	Name       string     `json:"name"`
+	Destroyed  bool       `json:"destroyed"`
+	Aliens     []int64    `json:"aliens"`
+	Roads      []Road     `json:"roads"`
+	Attributes Attributes `json:"attributes,omitempty"`
+}
+
+// RunState is a snapshot of a simulation between turns
+type RunState struct {
+	// Number of played turns
+	Turn      int         `json:"turn"`
+	Done      bool        `json:"done"`
+	EndReason EndReason   `json:"endReason,omitempty"`
+	Cities    []CityState `json:"cities"`
+	Aliens    []AlienInfo `json:"aliens"`
+}
+
+// NewRun creates a simulation run with provided configuration and lands aliens on the map.
+// Turns of the simulation are played with Step
+func (s *Simulation) NewRun(cfg SimulationConfig) (*Run, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	// All aliens take their actions simultaneously. There are three main simulation phases Spawn, Battle, Moving.
+	// Spawn. Create aliens and put every of them in a city chosen by the spawn strategy
+	r := &Run{
+		cfg:    cfg.withDefaults(),
+		rnd:    s.random(),
+		cities: s.getMapCopy(),
+		aliens: make([]alien, cfg.Aliens),
+
+		topology: s.topology,
+		schema:   s.schema,
+	}
+	r.names = r.cities.sortedNames()
+	r.view = newMapView(r.cities, r.names)
+
+	spawnCities, err := r.cfg.Spawn.Spawn(r.cfg.Aliens, r.view, r.rnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to spawn aliens: %w", err)
+	}
+	// spawn strategies may be implemented outside of the package, so their results are checked
+	if int64(len(spawnCities)) != r.cfg.Aliens {
+		return nil, fmt.Errorf("failed to spawn aliens: got %d landing cities for %d aliens", len(spawnCities), r.cfg.Aliens)
+	}
+	l := newLanding(r.view)
+	for _, name := range spawnCities {
+		if _, ok := r.cities[name]; !ok {
+			return nil, fmt.Errorf("failed to spawn aliens: cannot land in non existent city %s", name)
+		}
+		if l.isFull(name) {
+			return nil, fmt.Errorf("failed to spawn aliens: cannot land more aliens in full city %s", name)
+		}
+		l.land(name)
+	}
+	r.emit(SimulationStarted{Aliens: r.cfg.Aliens})
+	for id := range r.aliens {
+		r.aliens[id].visit(spawnCities[id])
+		r.cities[r.aliens[id].city].addAlien(int64(id))
+		r.emit(AlienSpawned{Alien: int64(id), City: r.aliens[id].city})
+	}
+	return r, nil
+}
+
+// emit keeps the event and delivers it to the sink
+func (r *Run) emit(e Event) {
+	if !r.cfg.DiscardEvents {
+		r.events = append(r.events, e)
+	}
+	if r.cfg.Sink != nil {
+		r.cfg.Sink.Emit(e)
+	}
+}
+
+// end finishes the simulation on the current turn
+func (r *Run) end(reason EndReason) {
+	r.ended = SimulationEnded{Turn: r.turn, Reason: reason}
+	r.done = true
+	r.emit(r.ended)
+}
+
+// Step plays a single turn of the simulation and returns true when the simulation is over.
+// Calling Step after the end of the simulation does nothing
+func (r *Run) Step() (done bool) {
+	if r.done {
+		return true
+	}
+	// Battle stage. Try to begin a battle in every city.
+	for _, name := range r.names {
+		c := r.cities[name]
+		if c.isDestroyed {
+			continue
+		}
+		// every point of defense of the city makes one more alien necessary to destroy it
+		defense, _ := c.attributes.Int(AttributeDefense)
+		if int64(len(c.aliens)) >= int64(r.cfg.DestructionThreshold)+defense {
+			// destroy the city and the aliens
+			c.isDestroyed = true
+			for _, id := range c.aliens {
+				r.aliens[id].isDead = true
+			}
+			fighters := make([]int64, len(c.aliens))
+			copy(fighters, c.aliens)
+			r.emit(BattleOccurred{Turn: r.turn, City: name, Aliens: fighters})
+			r.emit(CityDestroyed{Turn: r.turn, City: name, Aliens: fighters})
+		}
+	}
+
+	// Moving. Move every free alien to a new destination
+	aliveAliens := 0
+	freeAliens := 0
+	for id := range r.aliens {
+		a := &r.aliens[id]
+		// if alien is dead, don't move him
+		if a.isDead {
+			continue
+		}
+		// increment number of alive aliens for the current iterations
+		aliveAliens++
+		if a.isTrapped {
+			continue
+		}
+		// aliens on long roads neither fight nor choose where to go until they arrive
+		if a.transit != 0 {
+			switch r.cities.arrive(int64(id), a) {
+			case alienTrapped:
+				r.emit(AlienTrapped{Turn: r.turn, Alien: int64(id), City: a.city})
+				continue
+			case alienMoved:
+				r.emit(AlienArrived{Turn: r.turn, Alien: int64(id), City: a.city})
+			}
+			freeAliens++
+			continue
+		}
+		from := a.city
+		road, outcome := r.cities.moveAlien(int64(id), a, r.cfg.Movement, r.view, r.rnd)
+		switch outcome {
+		case alienTrapped:
+			r.emit(AlienTrapped{Turn: r.turn, Alien: int64(id), City: a.city})
+			continue
+		case alienMoved:
+			r.emit(AlienMoved{Turn: r.turn, Alien: int64(id), From: from, To: road.City, Direction: road.Direction, Transit: road.Transit})
+		}
+		freeAliens++
+	}
+
+	// if all aliens are dead or locked without ability to move then end the simulation.
+	switch {
+	case aliveAliens == 0:
+		r.end(EndReasonAllDead)
+	case freeAliens == 0:
+		r.end(EndReasonLocked)
+	case r.turn == r.cfg.MaxTurns-1:
+		r.end(EndReasonMaxTurns)
+	}
+	r.turn++
+	return r.done
+}
+
+// Done checks whether the simulation is over or not
+func (r *Run) Done() bool {
+	return r.done
+}
+
+// Turn returns the number of played turns
+func (r *Run) Turn() int {
+	return r.turn
+}
+
+// State returns a snapshot of cities and aliens. The snapshot doesn't change when the simulation goes on
+func (r *Run) State() RunState {
+	state := RunState{
+		Turn:   r.turn,
+		Done:   r.done,
+		Cities: make([]CityState, 0, len(r.names)),
+		Aliens: make([]AlienInfo, len(r.aliens)),
+	}
+	if r.done {
+		state.EndReason = r.ended.Reason
+	}
+	for _, name := range r.names {
+		c := r.cities[name]
+		aliens := make([]int64, len(c.aliens))
+		copy(aliens, c.aliens)
+		state.Cities = append(state.Cities, CityState{
+			Name:       name,
+			Destroyed:  c.isDestroyed,
+			Aliens:     aliens,
+			Roads:      r.view.Roads(name),
+			Attributes: c.attributes.copy(),
+		})
+	}
+	for id := range r.aliens {
+		state.Aliens[id] = r.aliens[id].info(int64(id))
+		// visits of the alien keep changing during the simulation
+		state.Aliens[id].visited = nil
+	}
+	return state
+}
+
+// Result returns the result of the simulation. The result shares the map with the run,
+// so it should be taken when the simulation is over
+func (r *Run) Result() *SimulationResult {
+	return &SimulationResult{
+		ResultMap: r.cities,
+		Aliens:    r.aliens,
+		Events:    r.events,
+		Turns:     r.turn,
+		EndReason: r.ended.Reason,
+		topology:  r.topology,
+		schema:    r.schema,
+	}
+}
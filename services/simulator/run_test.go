@@ -0,0 +1,296 @@
+package simulator
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSteppedRunEqualsRun(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	s.SetSeed(9)
+	whole, err := s.Run(DefaultSimulationConfig(10))
+	require.Nil(t, err)
+
+	s.SetSeed(9)
+	r, err := s.NewRun(DefaultSimulationConfig(10))
+	require.Nil(t, err)
+	steps := 0
+	for !r.Done() {
+		r.Step()
+		steps++
+	}
+	require.Equal(t, whole.Turns, steps)
+	require.Equal(t, whole, r.Result())
+}
+
+func TestRunStateIsSnapshot(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	s.SetSeed(1)
+	cfg := DefaultSimulationConfig(2)
+	cfg.Spawn = ListSpawn{Cities: []string{"Paris", "Porto"}}
+	r, err := s.NewRun(cfg)
+	require.Nil(t, err)
+
+	before := r.State()
+	require.Equal(t, 0, before.Turn)
+	require.False(t, before.Done)
+	require.Len(t, before.Cities, len(s.initialMap))
+	require.Equal(t, []AlienInfo{{ID: 0, City: "Paris"}, {ID: 1, City: "Porto"}}, before.Aliens)
+	for _, c := range before.Cities {
+		switch c.Name {
+		case "Paris":
+			require.Equal(t, []int64{0}, c.Aliens)
+		case "Porto":
+			require.Equal(t, []int64{1}, c.Aliens)
+			require.Equal(t, []Road{{Direction: "north", City: "Berlin"}, {Direction: "east", City: "Madrid"}}, c.Roads)
+		default:
+			require.Empty(t, c.Aliens)
+		}
+	}
+
+	r.Step()
+	after := r.State()
+	require.Equal(t, 1, after.Turn)
+	require.NotEqual(t, before.Aliens, after.Aliens)
+	require.Equal(t, []AlienInfo{{ID: 0, City: "Paris"}, {ID: 1, City: "Porto"}}, before.Aliens)
+}
+
+func TestStepAfterEndDoesNothing(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	s.SetSeed(1)
+	cfg := DefaultSimulationConfig(2)
+	cfg.Spawn = ListSpawn{Cities: []string{"Paris"}}
+	r, err := s.NewRun(cfg)
+	require.Nil(t, err)
+	require.True(t, r.Step())
+	events := len(r.Result().Events)
+	state := r.State()
+	require.True(t, state.Done)
+	require.Equal(t, EndReasonAllDead, state.EndReason)
+	for i := 0; i < 3; i++ {
+		require.True(t, r.Step())
+	}
+	require.Equal(t, 1, r.Turn())
+	require.Len(t, r.Result().Events, events)
+	require.Equal(t, state, r.State())
+}
+
+func TestRunAllowsCustomStopCondition(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	s.SetSeed(4)
+	r, err := s.NewRun(DefaultSimulationConfig(12))
+	require.Nil(t, err)
+	// stop as soon as Paris is destroyed or the simulation is over
+	parisDestroyed := func(state RunState) bool {
+		for _, c := range state.Cities {
+			if c.Name == "Paris" {
+				return c.Destroyed
+			}
+		}
+		return false
+	}
+	for !r.Step() && !parisDestroyed(r.State()) {
+	}
+	require.True(t, r.Done() || parisDestroyed(r.State()))
+}
+
+func TestNewRunRejectsInvalidConfig(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	_, err = s.NewRun(SimulationConfig{Aliens: -1, MaxTurns: 1, DestructionThreshold: 2})
+	require.EqualError(t, err, "number of aliens must not be negative, got -1")
+}
+
+// fixedSpawn is a custom spawn strategy which returns the cities as they are
+type fixedSpawn []string
+
+func (f fixedSpawn) Spawn(int64, MapView, *rand.Rand) ([]string, error) {
+	return f, nil
+}
+
+func TestNewRunChecksSpawnStrategyResult(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	for _, tc := range []struct {
+		spawn fixedSpawn
+		err   string
+	}{
+		{fixedSpawn{"Paris"}, "failed to spawn aliens: got 1 landing cities for 2 aliens"},
+		{fixedSpawn{"Paris", "Porto", "Paris"}, "failed to spawn aliens: got 3 landing cities for 2 aliens"},
+		{fixedSpawn{"Paris", "Atlantis"}, "failed to spawn aliens: cannot land in non existent city Atlantis"},
+	} {
+		cfg := DefaultSimulationConfig(2)
+		cfg.Spawn = tc.spawn
+		_, err := s.NewRun(cfg)
+		require.EqualError(t, err, tc.err)
+	}
+}
+
+func TestNewRunRespectsCapacityOfLandingCities(t *testing.T) {
+	input := "Gate capacity=1 west=Left\nLeft capacity=1 east=Gate\n"
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	cfg := DefaultSimulationConfig(2)
+	cfg.Spawn = ListSpawn{Cities: []string{"Gate"}}
+	_, err = s.NewRun(cfg)
+	require.EqualError(t, err, "failed to spawn aliens: cannot land more aliens in full city Gate")
+
+	// the uniform spawn lands the second alien in the city which is not full yet
+	cfg.Spawn = UniformSpawn{}
+	for seed := int64(0); seed < 10; seed++ {
+		s.SetSeed(seed)
+		r, err := s.NewRun(cfg)
+		require.Nil(t, err)
+		state := r.State()
+		require.Equal(t, "Gate", state.Cities[0].Name)
+		require.Len(t, state.Cities[0].Aliens, 1)
+		require.Len(t, state.Cities[1].Aliens, 1)
+	}
+}
+
+func TestDefenseRaisesDestructionThreshold(t *testing.T) {
+	input := "Fort defense=1 north=Camp\nCamp south=Fort\n"
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	cfg := DefaultSimulationConfig(2)
+	cfg.Spawn = ListSpawn{Cities: []string{"Fort"}}
+	cfg.Movement = StayPut{Probability: 1}
+	cfg.MaxTurns = 3
+	res, err := s.Run(cfg)
+	require.Nil(t, err)
+	require.Empty(t, res.DestroyedCities())
+
+	cfg.Aliens = 3
+	res, err = s.Run(cfg)
+	require.Nil(t, err)
+	require.Equal(t, []string{"Fort"}, res.DestroyedCities())
+}
+
+func TestAliensDontEnterFullCities(t *testing.T) {
+	input := "Gate capacity=1 west=Left east=Right\nLeft east=Gate\nRight west=Gate\n"
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	cfg := DefaultSimulationConfig(2)
+	cfg.Spawn = ListSpawn{Cities: []string{"Left", "Right"}}
+	cfg.MaxTurns = 1
+	r, err := s.NewRun(cfg)
+	require.Nil(t, err)
+	r.Step()
+	// the first alien takes the only place in the gate, the second one has to stay
+	state := r.State()
+	require.Equal(t, []int64{0}, state.Cities[0].Aliens)
+	require.Equal(t, "Gate", state.Cities[0].Name)
+	require.Equal(t, Attributes{"capacity": int64(1)}, state.Cities[0].Attributes)
+	require.Equal(t, "Right", state.Aliens[1].City)
+}
+
+func TestAliensInTransitDontFight(t *testing.T) {
+	input := "Boston east=Vladivostok:3\nVladivostok west=Boston:3\n"
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	cfg := DefaultSimulationConfig(2)
+	cfg.Spawn = ListSpawn{Cities: []string{"Boston", "Vladivostok"}}
+	cfg.MaxTurns = 4
+	res, err := s.Run(cfg)
+	require.Nil(t, err)
+	// aliens pass each other on the road and arrive three turns later
+	require.Empty(t, res.DestroyedCities())
+	require.Equal(t, []Event{
+		AlienMoved{Turn: 0, Alien: 0, From: "Boston", To: "Vladivostok", Direction: "east", Transit: 2},
+		AlienMoved{Turn: 0, Alien: 1, From: "Vladivostok", To: "Boston", Direction: "west", Transit: 2},
+		AlienArrived{Turn: 2, Alien: 0, City: "Vladivostok"},
+		AlienArrived{Turn: 2, Alien: 1, City: "Boston"},
+		AlienMoved{Turn: 3, Alien: 0, From: "Vladivostok", To: "Boston", Direction: "west", Transit: 2},
+		AlienMoved{Turn: 3, Alien: 1, From: "Boston", To: "Vladivostok", Direction: "east", Transit: 2},
+	}, res.Events[3:9])
+	require.Equal(t, 2, res.AlienStates()[0].Transit)
+}
+
+// leaveCities is a movement strategy which moves aliens along the first road only out of the cities
+type leaveCities map[string]bool
+
+func (l leaveCities) Move(a AlienInfo, roads []Road, _ MapView, _ *rand.Rand) (Road, bool) {
+	return roads[0], l[a.City]
+}
+
+func TestArrivalOfAliensInTransit(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		input    string
+		spawn    []string
+		movement MovementStrategy
+		// events of the first alien after it sets off
+		events []Event
+		state  AlienInfo
+	}{
+		{
+			name: "destination destroyed on the way",
+			input: `A east=C:3
+C west=A:3 east=B south=D
+B west=C
+D north=C`,
+			spawn:    []string{"A", "B", "D"},
+			movement: RandomWalk{},
+			events: []Event{
+				AlienMoved{Turn: 0, Alien: 0, From: "A", To: "C", Direction: "east", Transit: 2},
+				AlienTrapped{Turn: 2, Alien: 0, City: "C"},
+			},
+			state: AlienInfo{ID: 0, City: "C", Trapped: true},
+		},
+		{
+			name: "full destination",
+			input: `A east=C:3
+C capacity=1 west=A:3 south=B
+B north=C`,
+			spawn:    []string{"A", "B"},
+			movement: leaveCities{"A": true, "B": true},
+			events: []Event{
+				AlienMoved{Turn: 0, Alien: 0, From: "A", To: "C", Direction: "east", Transit: 2},
+			},
+			state: AlienInfo{ID: 0, City: "C", Transit: 1},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := createSimulation(strings.NewReader(tc.input), "testing")
+			require.Nil(t, err)
+			cfg := DefaultSimulationConfig(int64(len(tc.spawn)))
+			cfg.Spawn = ListSpawn{Cities: tc.spawn}
+			cfg.Movement = tc.movement
+			cfg.MaxTurns = 5
+			r, err := s.NewRun(cfg)
+			require.Nil(t, err)
+			for !r.Done() {
+				r.Step()
+			}
+
+			var events []Event
+			for _, e := range r.Result().Events {
+				switch e := e.(type) {
+				case AlienMoved:
+					if e.Alien == 0 {
+						events = append(events, e)
+					}
+				case AlienArrived:
+					if e.Alien == 0 {
+						events = append(events, e)
+					}
+				case AlienTrapped:
+					if e.Alien == 0 {
+						events = append(events, e)
+					}
+				}
+			}
+			require.Equal(t, tc.events, events)
+			alien := r.State().Aliens[0]
+			alien.visited = nil
+			require.Equal(t, tc.state, alien)
+		})
+	}
+}
@@ -4,29 +4,35 @@ import (
 	"fmt"
 	"io"
 	"math/rand"
-	"strconv"
+	"sort"
 	"strings"
-)
-
-const (
-	invasionDuration         = 10000
-	cityDestructionThreshold = 2
+	"time"
 )
 
 type planetMap map[string]*city
 
-// getRandomCity returns a random city on the map. If map is empty returns an empty string
-func (p planetMap) getRandomCity() string {
+// sortedNames returns names of all cities on the map in alphabetical order.
+// Iterating over the map in a stable order keeps simulations with the same seed reproducible
+func (p planetMap) sortedNames() []string {
+	names := make([]string, 0, len(p))
 	for name := range p {
-		return name
+		names = append(names, name)
 	}
-	return ""
+	sort.Strings(names)
+	return names
 }
 
-// mapDirection one of the fourth directions which city can have
+// mapDirection one of the directions which city can have
 type mapDirection struct {
 	directionType  string
 	directionValue string
+	// number of turns an alien spends on the road after the first one, 0 for ordinary roads
+	transit int
+}
+
+// road returns the read-only representation of the direction
+func (d mapDirection) road() Road {
+	return Road{Direction: d.directionType, City: d.directionValue, Transit: d.transit}
 }
 
 // city is the main part of a simulation
@@ -38,16 +44,114 @@ type city struct {
 	// City directions, can be a city name or empty string
 	directions []mapDirection
 
+	// attributes of the city, they never change during a simulation
+	attributes Attributes
+
 	// Contains the identifiers of all aliens which are located in the city
 	aliens []int64
 }
 
-// shuffleDirections shuffle directions slice order
-func (c *city) shuffleDirections() {
-	for i := range c.directions {
-		j := rand.Intn(i + 1)
-		c.directions[i], c.directions[j] = c.directions[j], c.directions[i]
+// copy returns a deep copy of the city
+func (c *city) copy() *city {
+	cp := &city{
+		name:        c.name,
+		isDestroyed: c.isDestroyed,
+		directions:  make([]mapDirection, len(c.directions)),
+		// attributes are read-only, so copies share them
+		attributes: c.attributes,
+	}
+	copy(cp.directions, c.directions)
+	if c.aliens != nil {
+		cp.aliens = make([]int64, len(c.aliens))
+		copy(cp.aliens, c.aliens)
 	}
+	return cp
+}
+
+// liveRoads returns roads of the city which lead to other not destroyed cities of the map.
+// These are the only roads aliens located in the city can move along
+func (p planetMap) liveRoads(name string) []Road {
+	c, ok := p[name]
+	if !ok {
+		return nil
+	}
+	roads := make([]Road, 0, len(c.directions))
+	for _, d := range c.directions {
+		// a road leading back to the city itself doesn't move an alien anywhere
+		if d.directionValue == name {
+			continue
+		}
+		destination, ok := p[d.directionValue]
+		if !ok || destination.isDestroyed {
+			continue
+		}
+		roads = append(roads, d.road())
+	}
+	return roads
+}
+
+// moveOutcome is a result of an attempt to move an alien
+type moveOutcome byte
+
+const (
+	alienMoved moveOutcome = iota
+	alienStayed
+	alienTrapped
+)
+
+// moveAlien moves the alien along a live road of its city chosen by the movement strategy
+// and returns the road. If there is no live road the alien is marked as trapped. If the strategy
+// decides to stay or picks a road which is not live the alien stays in its city.
+// An alien which sets off along a long road leaves its city at once and arrives in the destination
+// when the transit is over, see arrive
+func (p planetMap) moveAlien(id int64, a *alien, strategy MovementStrategy, view MapView, rnd *rand.Rand) (Road, moveOutcome) {
+	roads := p.liveRoads(a.city)
+	if len(roads) == 0 {
+		a.isTrapped = true
+		return Road{}, alienTrapped
+	}
+	road, ok := strategy.Move(a.info(id), roads, view, rnd)
+	if !ok || !containsRoad(roads, road) || p[road.City].isFull() {
+		return Road{}, alienStayed
+	}
+	// remove alien from its current city and add it to the new location
+	p[a.city].removeAlien(id)
+	if road.Transit != 0 {
+		a.city, a.transit = road.City, road.Transit
+		return road, alienMoved
+	}
+	p[road.City].addAlien(id)
+	a.visit(road.City)
+	return road, alienMoved
+}
+
+// arrive moves the alien one turn further along its road. The alien arrives in its destination when
+// the transit is over, an alien which comes to a full city waits on the road until there is a place in it.
+// A destination destroyed while the alien was on the road traps the alien in the ruins
+func (p planetMap) arrive(id int64, a *alien) moveOutcome {
+	if a.transit > 1 {
+		a.transit--
+		return alienStayed
+	}
+	destination := p[a.city]
+	if destination.isDestroyed {
+		a.transit = 0
+		a.isTrapped = true
+		return alienTrapped
+	}
+	if destination.isFull() {
+		return alienStayed
+	}
+	a.transit = 0
+	destination.addAlien(id)
+	a.visit(a.city)
+	return alienMoved
+}
+
+// isFull checks whether the city has as many aliens as its capacity allows or not
+func (c *city) isFull() bool {
+	capacity, ok := c.attributes.Int(AttributeCapacity)
+	return ok && int64(len(c.aliens)) >= capacity
 }
 
 // addAlien adds the alien to the city. If the alien is already in the city, does nothing
@@ -70,66 +174,203 @@ func (c *city) removeAlien(alienID int64) {
 	}
 }
 
-// battleMessage returns a battle message based on the city name and aliens in it
-func (c *city) battleMessage() string {
-	builder := strings.Builder{}
-	builder.WriteString("Aliens: ")
-	for i, a := range c.aliens {
-		builder.WriteString("👾")
-		builder.WriteString(strconv.Itoa(int(a)))
-		if i != len(c.aliens)-1 {
-			builder.WriteString(", ")
-		}
-	}
-	builder.WriteString(fmt.Sprintf(" have met in the city of %s. ⚔ Battle destroyed the city.", c.name))
-
-	return builder.String()
-}
-
 // alien is an earth invader which is moving from one city to another
 type alien struct {
-	// City where the alien is located
+	// City where the alien is located or the destination of its road if the alien is in transit
 	city string
 
+	// number of turns left before the alien arrives in its city, 0 if the alien is in the city
+	transit int
+
 	// specifies either alien is alive and can move or dead
 	isDead bool
+
+	// specifies either alien is alive but can't leave its city as all roads lead to destroyed cities
+	isTrapped bool
+
+	// number of visits of every city the alien has been in
+	visited map[string]int
+}
+
+// visit puts the alien in the city and remembers the visit
+func (a *alien) visit(cityName string) {
+	if a.visited == nil {
+		a.visited = map[string]int{}
+	}
+	a.city = cityName
+	a.visited[cityName]++
+}
+
+// info returns a read-only snapshot of the alien
+func (a *alien) info(id int64) AlienInfo {
+	return AlienInfo{
+		ID:      id,
+		City:    a.city,
+		Dead:    a.isDead,
+		Trapped: a.isTrapped,
+		Transit: a.transit,
+		visited: a.visited,
+	}
 }
 
 // Simulation allows running invasion scenarios with different number of aliens
 type Simulation struct {
 	initialMap planetMap
+	// topology of the map, nil for the default one
+	topology *Topology
+	// attributes of cities, nil if the map declares no custom attributes
+	schema *attributeSchema
+
+	// source of randomness for the simulation, if nil a time based source is used
+	source rand.Source
+}
+
+// SetSeed makes the simulation draw its random numbers from a source created with the provided seed.
+// Simulations of the same map with the same seed and number of aliens always give the same result
+func (s *Simulation) SetSeed(seed int64) {
+	s.source = rand.NewSource(seed)
+}
+
+// Topology returns the set of directions the map of the simulation uses
+func (s *Simulation) Topology() *Topology {
+	if s.topology == nil {
+		return DefaultTopology()
+	}
+	return s.topology
 }
 
-// getMapCopy returns copy af a simulation map
+// SetSource sets the source of randomness used by the simulation
+func (s *Simulation) SetSource(src rand.Source) {
+	s.source = src
+}
+
+// random returns a random generator based on the simulation source
+func (s *Simulation) random() *rand.Rand {
+	if s.source == nil {
+		s.source = rand.NewSource(time.Now().UnixNano())
+	}
+	return rand.New(s.source)
+}
+
+// getMapCopy returns a deep copy of a simulation map, so every run works on its own cities
+// and never changes the initial map
 func (s *Simulation) getMapCopy() planetMap {
-	cp := make(planetMap)
+	cp := make(planetMap, len(s.initialMap))
 	for name, c := range s.initialMap {
-		cp[name] = c
+		cp[name] = c.copy()
 	}
 
 	return cp
 }
 
-// SimulationResult represents a final result of a simulation, contains resulted aliens and logs of simulation.
+// SimulationResult represents a final result of a simulation, contains resulted aliens and events of simulation.
 type SimulationResult struct {
 	ResultMap planetMap
 	Aliens    []alien
-	Logs      []string
+	Events    []Event
+
+	// Number of played turns and the reason why the simulation is over
+	Turns     int
+	EndReason EndReason
+
+	// topology of the map, nil for the default one
+	topology *Topology
+	// attributes of cities, nil if the map declares no custom attributes
+	schema *attributeSchema
 }
 
-// PrintResultMap prints out result state of a map in the standard map format
-func (sr *SimulationResult) PrintResultMap(out io.Writer) error {
+// Logs returns human-readable messages about the simulation events
+func (sr *SimulationResult) Logs() []string {
+	return RenderEvents(sr.Events)
+}
+
+// SurvivingCities returns names of cities which are not destroyed in alphabetical order
+func (sr *SimulationResult) SurvivingCities() []string {
+	return sr.citiesByState(false)
+}
+
+// DestroyedCities returns names of destroyed cities in alphabetical order
+func (sr *SimulationResult) DestroyedCities() []string {
+	return sr.citiesByState(true)
+}
+
+// citiesByState returns names of cities which are either destroyed or not in alphabetical order
+func (sr *SimulationResult) citiesByState(destroyed bool) []string {
+	names := make([]string, 0)
+	for _, name := range sr.ResultMap.sortedNames() {
+		if sr.ResultMap[name].isDestroyed == destroyed {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// DestroyedPopulation returns the total population of destroyed cities
+func (sr *SimulationResult) DestroyedPopulation() int64 {
+	total := int64(0)
 	for _, c := range sr.ResultMap {
+		if population, ok := c.attributes.Int(AttributePopulation); ok && c.isDestroyed {
+			total += population
+		}
+	}
+	return total
+}
+
+// AlienStates returns final states of all aliens ordered by their identifiers
+func (sr *SimulationResult) AlienStates() []AlienInfo {
+	states := make([]AlienInfo, len(sr.Aliens))
+	for id := range sr.Aliens {
+		states[id] = sr.Aliens[id].info(int64(id))
+	}
+	return states
+}
+
+// writeMapHeader writes declarations of the topology and custom attributes, maps which have them
+// can be read back only with the declarations
+func writeMapHeader(out io.Writer, topology *Topology, schema *attributeSchema) error {
+	if topology != nil && topology != DefaultTopology() {
+		if _, err := fmt.Fprintf(out, "%s %s\n", topologyDirective, topology); err != nil {
+			return err
+		}
+	}
+	if schema == nil {
+		return nil
+	}
+	for _, name := range schema.custom() {
+		if _, err := fmt.Fprintln(out, attributeDeclaration(schema, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrintResultMap prints out result state of a map in the standard map format,
+// directions go in the canonical order of the map topology
+func (sr *SimulationResult) PrintResultMap(out io.Writer) error {
+	if err := writeMapHeader(out, sr.topology, sr.schema); err != nil {
+		return fmt.Errorf("failed to print out result map: %w", err)
+	}
+	schema := sr.schema
+	if schema == nil {
+		schema = newAttributeSchema()
+	}
+	topology := sr.topology
+	if topology == nil {
+		topology = DefaultTopology()
+	}
+	for _, name := range sr.ResultMap.sortedNames() {
+		c := sr.ResultMap[name]
 		if c.isDestroyed {
 			continue
 		}
 		output := strings.Builder{}
-		output.WriteString(c.name)
-		for _, d := range c.directions {
+		output.WriteString(quoteCityName(c.name))
+		output.WriteString(formatAttributes(schema, c.attributes))
+		for _, d := range topology.canonicalDirections(c.directions) {
 			if sr.ResultMap[d.directionValue].isDestroyed {
 				continue
 			}
-			output.WriteString(fmt.Sprintf(" %s=%s", d.directionType, d.directionValue))
+			output.WriteString(fmt.Sprintf(" %s=%s", d.directionType, formatRoad(d.directionValue, d.transit)))
 		}
 		output.WriteByte('\n')
 		_, err := out.Write([]byte(output.String()))
@@ -141,80 +382,15 @@ func (sr *SimulationResult) PrintResultMap(out io.Writer) error {
 	return nil
 }
 
-// Run runs simulation with provided number of aliens, returns result of a simulation
-// with battle logs, aliens and final state of a map
-func (s *Simulation) Run(numberOfAliens int64) *SimulationResult {
-	// All aliens take their actions simultaneously. There are three main simulation phases Spawn, Battle, Moving.
-	// Spawn. Create aliens and put every of them in a random city
-	simulationMap := s.getMapCopy()
-	aliens := make([]alien, numberOfAliens)
-	logs := []string{fmt.Sprintf("Simulate invasion with %d aliens", numberOfAliens)}
-
-	for id := range aliens {
-		aliens[id].city = simulationMap.getRandomCity()
-		simulationMap[aliens[id].city].addAlien(int64(id))
-	}
-
-	for i := 0; i < invasionDuration; i++ {
-		// Battle stage. Try to begin a battle in every city.
-		for _, c := range simulationMap {
-			if c.isDestroyed {
-				continue
-			}
-			if len(c.aliens) >= cityDestructionThreshold {
-				// destroy the city and the aliens
-				c.isDestroyed = true
-				for _, id := range c.aliens {
-					aliens[id].isDead = true
-				}
-				logs = append(logs, c.battleMessage())
-			}
-		}
-
-		// Moving. Move every alien to a new destination
-		moves := 0
-		aliveAliens := 0
-		for id, a := range aliens {
-			// if alien is dead, don't move him
-			if a.isDead {
-				continue
-			}
-			// increment number of alive aliens for the current iterations
-			aliveAliens++
-			// shuffle directions order in order to start with a random one
-			simulationMap[a.city].shuffleDirections()
-			for _, direction := range simulationMap[a.city].directions {
-				// if the mapDirection leads to a not destroyed city move alien to it
-				if !simulationMap[a.city].isDestroyed {
-					// remove alien from its current city
-					simulationMap[a.city].removeAlien(int64(id))
-					// add alien to the new location
-					simulationMap[direction.directionValue].addAlien(int64(id))
-					// update alien location
-					aliens[id].city = direction.directionValue
-					// increment moves counter
-					moves++
-					break
-				}
-			}
-		}
-		// if all aliens are dead or locked without ability to move then end the simulation.
-		if aliveAliens == 0 {
-			logs = append(logs, fmt.Sprintf("All aliens are dead, simulations is over on turn number %d", i))
-			break
-		} else if moves == 0 {
-			logs = append(logs, fmt.Sprintf("All aliens are either dead or locked, simulations is over on turn number %d", i))
-			break
-		}
-
-		if i == invasionDuration-1 {
-			logs = append(logs, fmt.Sprintf("%d turns are finished. Simulation is over", invasionDuration))
-		}
+// Run runs simulation with provided configuration, returns result of a simulation
+// with events, aliens and final state of a map. Events are also delivered to the configured sink
+// as soon as they happen
+func (s *Simulation) Run(cfg SimulationConfig) (*SimulationResult, error) {
+	r, err := s.NewRun(cfg)
+	if err != nil {
+		return nil, err
 	}
-
-	return &SimulationResult{
-		ResultMap: simulationMap,
-		Aliens:    aliens,
-		Logs:      logs,
+	for !r.Step() {
 	}
+	return r.Result(), nil
 }
@@ -2,6 +2,7 @@ package simulator
 
 import (
 	"github.com/stretchr/testify/require"
+	"math/rand"
 	"strings"
 	"testing"
 )
@@ -26,10 +27,286 @@ func TestSimulationResultPrintCorrectMap(t *testing.T) {
 	require.Equal(t, "Bolton\n", builder.String())
 }
 
-func TestCityPrintsOutCorrectBattleMessage(t *testing.T) {
-	c := city{
-		name:   "Boston",
-		aliens: []int64{11, 2, 5},
+const testMap = `Paris south=Vladivostok west=San_Francisco east=Moscow
+Boston north=New_York west=Porto east=Vladivostok
+New_York north=London south=Belgrade
+Moscow north=Prague west=Paris east=Berlin
+London north=Madrid south=Warsaw west=Porto east=Paris
+Berlin south=Belgrade west=Porto east=Prague
+San_Francisco north=Madrid west=New_York
+Prague north=Warsaw south=Boston west=Belgrade
+Porto north=Berlin east=Madrid
+Warsaw north=Paris south=Porto west=Prague
+Belgrade south=Berlin west=London east=Warsaw
+Madrid north=Boston south=Prague east=London
+Vladivostok north=Prague west=Porto`
+
+// runSeeded creates a fresh simulation from the test map and runs it with the provided seed
+func runSeeded(t *testing.T, seed int64, numberOfAliens int64) (*SimulationResult, string) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	s.SetSeed(seed)
+	res, err := s.Run(DefaultSimulationConfig(numberOfAliens))
+	require.Nil(t, err)
+	builder := strings.Builder{}
+	require.Nil(t, res.PrintResultMap(&builder))
+	return res, builder.String()
+}
+
+func TestSimulationWithSameSeedIsReproducible(t *testing.T) {
+	for _, seed := range []int64{0, 1, 42, 1633036800} {
+		first, firstMap := runSeeded(t, seed, 10)
+		for i := 0; i < 5; i++ {
+			next, nextMap := runSeeded(t, seed, 10)
+			require.Equal(t, first.Events, next.Events)
+			require.Equal(t, first.Aliens, next.Aliens)
+			require.Equal(t, firstMap, nextMap)
+		}
+	}
+}
+
+func TestCityCopyDoesNotShareState(t *testing.T) {
+	c := &city{
+		name:       "Boston",
+		directions: []mapDirection{{directionType: "west", directionValue: "Porto"}},
+		aliens:     []int64{1},
+	}
+	cp := c.copy()
+	require.Equal(t, c, cp)
+	cp.isDestroyed = true
+	cp.directions[0].directionValue = "Madrid"
+	cp.addAlien(2)
+	cp.removeAlien(1)
+	require.False(t, c.isDestroyed)
+	require.Equal(t, "Porto", c.directions[0].directionValue)
+	require.Equal(t, []int64{1}, c.aliens)
+}
+
+func TestSimulationRunsDoNotChangeInitialMap(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	initial := s.getMapCopy()
+	s.SetSeed(7)
+	_, err = s.Run(DefaultSimulationConfig(20))
+	require.Nil(t, err)
+	require.Equal(t, initial, s.initialMap)
+}
+
+func TestSimulationRunsDoNotAffectEachOther(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	fresh, freshMap := runSeeded(t, 3, 10)
+	for i := 0; i < 10; i++ {
+		s.SetSeed(3)
+		res, err := s.Run(DefaultSimulationConfig(10))
+		require.Nil(t, err)
+		builder := strings.Builder{}
+		require.Nil(t, res.PrintResultMap(&builder))
+		require.Equal(t, fresh.Events, res.Events)
+		require.Equal(t, freshMap, builder.String())
+	}
+}
+
+func TestSimulationResultsDoNotShareCities(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	s.SetSeed(11)
+	first, err := s.Run(DefaultSimulationConfig(10))
+	require.Nil(t, err)
+	second, err := s.Run(DefaultSimulationConfig(10))
+	require.Nil(t, err)
+	for name, c := range first.ResultMap {
+		require.NotSame(t, c, second.ResultMap[name])
+		require.NotSame(t, c, s.initialMap[name])
 	}
-	require.Equal(t, "Aliens: 👾11, 👾2, 👾5 have met in the city of Boston. ⚔ Battle destroyed the city.", c.battleMessage())
+}
+
+func TestAlienMovesOnlyToLiveNeighbours(t *testing.T) {
+	cases := []struct {
+		name         string
+		cities       planetMap
+		expectedLive []string
+	}{
+		{
+			name: "isolated city",
+			cities: planetMap{
+				"Boston": {name: "Boston"},
+			},
+		},
+		{
+			name: "road back to the city itself",
+			cities: planetMap{
+				"Boston": {name: "Boston", directions: []mapDirection{{directionType: "north", directionValue: "Boston"}}},
+			},
+		},
+		{
+			name: "all neighbours destroyed",
+			cities: planetMap{
+				"Boston": {name: "Boston", directions: []mapDirection{
+					{directionType: "north", directionValue: "Porto"},
+					{directionType: "south", directionValue: "Madrid"},
+				}},
+				"Porto":  {name: "Porto", isDestroyed: true},
+				"Madrid": {name: "Madrid", isDestroyed: true},
+			},
+		},
+		{
+			name: "one neighbour destroyed",
+			cities: planetMap{
+				"Boston": {name: "Boston", directions: []mapDirection{
+					{directionType: "north", directionValue: "Porto"},
+					{directionType: "south", directionValue: "Madrid"},
+				}},
+				"Porto":  {name: "Porto", isDestroyed: true},
+				"Madrid": {name: "Madrid"},
+			},
+			expectedLive: []string{"Madrid"},
+		},
+		{
+			name: "one-way road out of the city",
+			cities: planetMap{
+				"Boston": {name: "Boston", directions: []mapDirection{{directionType: "east", directionValue: "Porto"}}},
+				"Porto":  {name: "Porto"},
+			},
+			expectedLive: []string{"Porto"},
+		},
+		{
+			name: "one-way road into the city",
+			cities: planetMap{
+				"Boston": {name: "Boston"},
+				"Porto":  {name: "Porto", directions: []mapDirection{{directionType: "west", directionValue: "Boston"}}},
+			},
+		},
+		{
+			name: "all neighbours alive",
+			cities: planetMap{
+				"Boston": {name: "Boston", directions: []mapDirection{
+					{directionType: "north", directionValue: "Porto"},
+					{directionType: "south", directionValue: "Madrid"},
+				}},
+				"Porto":  {name: "Porto"},
+				"Madrid": {name: "Madrid"},
+			},
+			expectedLive: []string{"Porto", "Madrid"},
+		},
+	}
+
+	for _, tc := range cases {
+		var live []string
+		for _, r := range tc.cities.liveRoads("Boston") {
+			live = append(live, r.City)
+		}
+		require.Equal(t, tc.expectedLive, live, tc.name)
+
+		tc.cities["Boston"].addAlien(0)
+		a := &alien{city: "Boston"}
+		_, outcome := tc.cities.moveAlien(0, a, RandomWalk{}, newMapView(tc.cities, tc.cities.sortedNames()), rand.New(rand.NewSource(1)))
+		if len(tc.expectedLive) == 0 {
+			require.Equal(t, alienTrapped, outcome, tc.name)
+			require.True(t, a.isTrapped, tc.name)
+			require.Equal(t, "Boston", a.city, tc.name)
+			require.Equal(t, []int64{0}, tc.cities["Boston"].aliens, tc.name)
+			continue
+		}
+		require.Equal(t, alienMoved, outcome, tc.name)
+		require.False(t, a.isTrapped, tc.name)
+		require.Contains(t, tc.expectedLive, a.city, tc.name)
+		require.Empty(t, tc.cities["Boston"].aliens, tc.name)
+		require.Equal(t, []int64{0}, tc.cities[a.city].aliens, tc.name)
+	}
+}
+
+func TestSimulationEndsWhenAllAliensAreTrapped(t *testing.T) {
+	input := `Boston east=Porto
+Porto east=Madrid
+Madrid west=Porto
+Lisbon north=Madrid`
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	s.SetSeed(1)
+	// two aliens destroy Madrid on the first turn, the third one is trapped in Porto as the only road leads to ruins
+	cfg := DefaultSimulationConfig(3)
+	cfg.Spawn = ListSpawn{Cities: []string{"Madrid", "Madrid", "Boston"}}
+	res, err := s.Run(cfg)
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"Simulate invasion with 3 aliens",
+		"Aliens: 👾0, 👾1 have met in the city of Madrid. ⚔ Battle destroyed the city.",
+		"Alien 👾2 is trapped in the city of Porto. All roads lead to ruins.",
+		"All aliens are either dead or locked, simulations is over on turn number 1",
+	}, res.Logs())
+	require.Equal(t, "Porto", res.Aliens[2].city)
+	require.True(t, res.Aliens[2].isTrapped)
+}
+
+func TestSimulationResultReportsFinalState(t *testing.T) {
+	res := SimulationResult{
+		ResultMap: planetMap{
+			"London": {name: "London", isDestroyed: true},
+			"Bolton": {name: "Bolton", aliens: []int64{1}},
+			"Albany": {name: "Albany"},
+		},
+		Aliens: []alien{{city: "London", isDead: true}, {city: "Bolton", isTrapped: true}},
+	}
+	require.Equal(t, []string{"Albany", "Bolton"}, res.SurvivingCities())
+	require.Equal(t, []string{"London"}, res.DestroyedCities())
+	require.Equal(t, []AlienInfo{
+		{ID: 0, City: "London", Dead: true},
+		{ID: 1, City: "Bolton", Trapped: true},
+	}, res.AlienStates())
+}
+
+func TestPrintResultMapDeclaresTopology(t *testing.T) {
+	input := "@topology hex\nParis northeast=Berlin\nBerlin southwest=Paris\n"
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	s.SetSeed(1)
+	res, err := s.Run(DefaultSimulationConfig(0))
+	require.Nil(t, err)
+	builder := strings.Builder{}
+	require.Nil(t, res.PrintResultMap(&builder))
+	require.Equal(t, "@topology hex\nBerlin southwest=Paris\nParis northeast=Berlin\n", builder.String())
+}
+
+func TestPrintResultMapWritesDirectionsInCanonicalOrder(t *testing.T) {
+	input := "Paris west=Lyon south=Nice east=Nancy north=Lille\nLille south=Paris\nNice north=Paris\nLyon east=Paris\nNancy west=Paris\n"
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	s.SetSeed(1)
+	res, err := s.Run(DefaultSimulationConfig(0))
+	require.Nil(t, err)
+	builder := strings.Builder{}
+	require.Nil(t, res.PrintResultMap(&builder))
+	require.Equal(t, "Lille south=Paris\nLyon east=Paris\nNancy west=Paris\nNice north=Paris\nParis north=Lille east=Nancy south=Nice west=Lyon\n", builder.String())
+}
+
+func TestPrintResultMapRoundTripsUnicodeNames(t *testing.T) {
+	input := "\"New York\" east=Москва\nМосква west=\"New York\" south=São_Paulo\nSão_Paulo north=Москва\n"
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	s.SetSeed(1)
+	res, err := s.Run(DefaultSimulationConfig(0))
+	require.Nil(t, err)
+	builder := strings.Builder{}
+	require.Nil(t, res.PrintResultMap(&builder))
+	require.Equal(t, "\"New York\" east=Москва\nSão_Paulo north=Москва\nМосква south=São_Paulo west=\"New York\"\n", builder.String())
+
+	again, err := createSimulation(strings.NewReader(builder.String()), "testing")
+	require.Nil(t, err)
+	require.Equal(t, s.initialMap, again.initialMap)
+}
+
+func TestSimulationResultReportsDestroyedPopulation(t *testing.T) {
+	input := "@attribute capital bool\nParis population=2100000 capital=true north=Berlin\nBerlin population=3600000 south=Paris\n"
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	cfg := DefaultSimulationConfig(2)
+	cfg.Spawn = ListSpawn{Cities: []string{"Paris"}}
+	res, err := s.Run(cfg)
+	require.Nil(t, err)
+	require.Equal(t, int64(2100000), res.DestroyedPopulation())
+
+	builder := strings.Builder{}
+	require.Nil(t, res.PrintResultMap(&builder))
+	require.Equal(t, "@attribute capital bool\nBerlin population=3600000\n", builder.String())
 }
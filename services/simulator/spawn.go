@@ -0,0 +1,275 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SpawnStrategy decides where aliens land at the beginning of a simulation
+type SpawnStrategy interface {
+	// Spawn returns landing cities for the provided number of aliens,
+	// the i-th city is the landing place of the alien with id i
+	Spawn(numberOfAliens int64, m MapView, rnd *rand.Rand) ([]string, error)
+}
+
+var (
+	errEmptyMap   = errors.New("cannot spawn aliens on a map without cities")
+	errFullCities = errors.New("cannot spawn aliens, all cities are full")
+)
+
+// landing counts aliens landed in every city, so strategies don't land more aliens in a city
+// than its capacity allows
+type landing struct {
+	m      MapView
+	landed map[string]int64
+}
+
+// newLanding creates a landing on the map without aliens
+func newLanding(m MapView) *landing {
+	return &landing{m: m, landed: map[string]int64{}}
+}
+
+// isFull checks whether the city has as many landed aliens as its capacity allows or not
+func (l *landing) isFull(name string) bool {
+	capacity, ok := l.m.Attributes(name).Int(AttributeCapacity)
+	return ok && l.landed[name] >= capacity
+}
+
+// land lands an alien in the city and reports whether the city became full
+func (l *landing) land(name string) bool {
+	l.landed[name]++
+	return l.isFull(name)
+}
+
+// UniformSpawn lands every alien in a city chosen uniformly at random among cities which are not full
+type UniformSpawn struct{}
+
+func (UniformSpawn) Spawn(numberOfAliens int64, m MapView, rnd *rand.Rand) ([]string, error) {
+	return pickUniformly(numberOfAliens, m.Cities(), m, rnd)
+}
+
+// WeightedSpawn lands aliens in random cities proportionally to the city weights.
+// Cities without a weight and full cities are never chosen
+type WeightedSpawn struct {
+	Weights map[string]float64
+}
+
+func (w WeightedSpawn) Spawn(numberOfAliens int64, m MapView, rnd *rand.Rand) ([]string, error) {
+	names := make([]string, 0, len(w.Weights))
+	for name, weight := range w.Weights {
+		if !m.HasCity(name) {
+			return nil, fmt.Errorf("cannot spawn aliens in non existent city %s", name)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("weight of the city %s must not be negative, got %g", name, weight)
+		}
+		if weight > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, errors.New("at least one city must have a positive spawn weight")
+	}
+	// sort names in order to keep the result reproducible for the same random generator
+	sort.Strings(names)
+	cumulative, total := cumulativeWeights(names, w.Weights)
+
+	l := newLanding(m)
+	cities := make([]string, numberOfAliens)
+	for i := range cities {
+		if len(names) == 0 {
+			return nil, errFullCities
+		}
+		r := rnd.Float64() * total
+		idx := sort.Search(len(cumulative), func(j int) bool { return cumulative[j] > r })
+		if idx == len(cumulative) {
+			idx = len(cumulative) - 1
+		}
+		cities[i] = names[idx]
+		if l.land(names[idx]) {
+			names = append(names[:idx], names[idx+1:]...)
+			cumulative, total = cumulativeWeights(names, w.Weights)
+		}
+	}
+	return cities, nil
+}
+
+// cumulativeWeights returns cumulative sums of weights of the cities and the total weight
+func cumulativeWeights(names []string, weights map[string]float64) ([]float64, float64) {
+	cumulative := make([]float64, len(names))
+	total := 0.0
+	for i, name := range names {
+		total += weights[name]
+		cumulative[i] = total
+	}
+	return cumulative, total
+}
+
+// ClusterSpawn lands aliens uniformly at random in cities which are reachable
+// from the center city by at most Hops roads and are not full
+type ClusterSpawn struct {
+	Center string
+	Hops   int
+}
+
+func (c ClusterSpawn) Spawn(numberOfAliens int64, m MapView, rnd *rand.Rand) ([]string, error) {
+	if !m.HasCity(c.Center) {
+		return nil, fmt.Errorf("cannot spawn aliens around non existent city %s", c.Center)
+	}
+	if c.Hops < 0 {
+		return nil, fmt.Errorf("number of hops must not be negative, got %d", c.Hops)
+	}
+	// breadth first search from the center city limited by the number of hops
+	reached := map[string]bool{c.Center: true}
+	cluster := []string{c.Center}
+	frontier := []string{c.Center}
+	for hop := 0; hop < c.Hops && len(frontier) != 0; hop++ {
+		var next []string
+		for _, name := range frontier {
+			for _, r := range m.Roads(name) {
+				if reached[r.City] {
+					continue
+				}
+				reached[r.City] = true
+				cluster = append(cluster, r.City)
+				next = append(next, r.City)
+			}
+		}
+		frontier = next
+	}
+	sort.Strings(cluster)
+
+	return pickUniformly(numberOfAliens, cluster, m, rnd)
+}
+
+// ListSpawn lands aliens in the listed cities one by one, starting over when the list is exhausted
+type ListSpawn struct {
+	Cities []string
+}
+
+func (l ListSpawn) Spawn(numberOfAliens int64, m MapView, _ *rand.Rand) ([]string, error) {
+	if len(l.Cities) == 0 {
+		return nil, errors.New("list of spawn cities must not be empty")
+	}
+	for _, name := range l.Cities {
+		if !m.HasCity(name) {
+			return nil, fmt.Errorf("cannot spawn aliens in non existent city %s", name)
+		}
+	}
+	cities := make([]string, numberOfAliens)
+	for i := range cities {
+		cities[i] = l.Cities[i%len(l.Cities)]
+	}
+	return cities, nil
+}
+
+// PopulationSpawn lands aliens in random cities proportionally to the population attribute of cities.
+// Cities without population and full cities are never chosen
+type PopulationSpawn struct{}
+
+func (PopulationSpawn) Spawn(numberOfAliens int64, m MapView, rnd *rand.Rand) ([]string, error) {
+	weights := map[string]float64{}
+	for _, name := range m.Cities() {
+		if population, ok := m.Attributes(name).Int(AttributePopulation); ok && population > 0 {
+			weights[name] = float64(population)
+		}
+	}
+	if len(weights) == 0 {
+		return nil, errors.New("at least one city must have a positive population")
+	}
+	return WeightedSpawn{Weights: weights}.Spawn(numberOfAliens, m, rnd)
+}
+
+// pickUniformly picks a random city from names for every alien, a city is not picked any more once it is full
+func pickUniformly(numberOfAliens int64, names []string, m MapView, rnd *rand.Rand) ([]string, error) {
+	if len(names) == 0 {
+		return nil, errEmptyMap
+	}
+	open := make([]string, len(names))
+	copy(open, names)
+	l := newLanding(m)
+	cities := make([]string, numberOfAliens)
+	for i := range cities {
+		if len(open) == 0 {
+			return nil, errFullCities
+		}
+		idx := rnd.Intn(len(open))
+		cities[i] = open[idx]
+		if l.land(open[idx]) {
+			open = append(open[:idx], open[idx+1:]...)
+		}
+	}
+	return cities, nil
+}
+
+// ParseSpawnStrategy creates a spawn strategy from its textual description. Supported descriptions are
+// uniform, weighted:City1=3,City2=1, cluster:City:hops, list:City1,City2 and population
+func ParseSpawnStrategy(spec string) (SpawnStrategy, error) {
+	kind, args := spec, ""
+	if i := strings.IndexByte(spec, ':'); i != -1 {
+		kind, args = spec[:i], spec[i+1:]
+	}
+	switch kind {
+	case "uniform":
+		if args != "" {
+			return nil, fmt.Errorf("uniform spawn strategy takes no arguments, got %s", args)
+		}
+		return UniformSpawn{}, nil
+	case "weighted":
+		weights := map[string]float64{}
+		for _, pair := range splitList(args) {
+			i := strings.IndexByte(pair, '=')
+			if i == -1 {
+				return nil, fmt.Errorf("expected city=weight in weighted spawn strategy, got %s", pair)
+			}
+			weight, err := strconv.ParseFloat(pair[i+1:], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid weight of the city %s: %w", pair[:i], err)
+			}
+			weights[pair[:i]] = weight
+		}
+		if len(weights) == 0 {
+			return nil, errors.New("weighted spawn strategy requires at least one city=weight pair")
+		}
+		return WeightedSpawn{Weights: weights}, nil
+	case "cluster":
+		i := strings.LastIndexByte(args, ':')
+		if i == -1 {
+			return nil, fmt.Errorf("expected cluster:city:hops, got %s", spec)
+		}
+		hops, err := strconv.Atoi(args[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid number of hops in cluster spawn strategy: %w", err)
+		}
+		return ClusterSpawn{Center: normalizeCityName(args[:i]), Hops: hops}, nil
+	case "list":
+		cities := splitList(args)
+		if len(cities) == 0 {
+			return nil, errors.New("list spawn strategy requires at least one city")
+		}
+		return ListSpawn{Cities: cities}, nil
+	case "population":
+		if args != "" {
+			return nil, fmt.Errorf("population spawn strategy takes no arguments, got %s", args)
+		}
+		return PopulationSpawn{}, nil
+	default:
+		return nil, fmt.Errorf("unknown spawn strategy %s, expected one of uniform,weighted,cluster,list,population", kind)
+	}
+}
+
+// splitList splits comma separated list of city names skipping empty items, names are normalized
+// the same way the parser does it
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, normalizeCityName(item))
+		}
+	}
+	return items
+}
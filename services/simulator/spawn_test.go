@@ -0,0 +1,168 @@
+package simulator
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testView creates a map view from the test map
+func testView(t *testing.T) MapView {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	return newMapView(s.initialMap, s.initialMap.sortedNames())
+}
+
+// countCities counts how many times every city occurs in cities
+func countCities(cities []string) map[string]int {
+	counts := map[string]int{}
+	for _, c := range cities {
+		counts[c]++
+	}
+	return counts
+}
+
+func TestUniformSpawnIsUniform(t *testing.T) {
+	view := testView(t)
+	const perCity = 2000
+	total := int64(perCity * len(view.Cities()))
+	cities, err := UniformSpawn{}.Spawn(total, view, rand.New(rand.NewSource(1)))
+	require.Nil(t, err)
+	counts := countCities(cities)
+	require.Len(t, counts, len(view.Cities()))
+	for name, count := range counts {
+		require.InDeltaf(t, perCity, count, perCity*0.1, "city %s got %d aliens", name, count)
+	}
+}
+
+func TestUniformSpawnFailsOnEmptyMap(t *testing.T) {
+	_, err := UniformSpawn{}.Spawn(1, newMapView(planetMap{}, nil), rand.New(rand.NewSource(1)))
+	require.EqualError(t, err, "cannot spawn aliens on a map without cities")
+}
+
+func TestWeightedSpawnFollowsWeights(t *testing.T) {
+	view := testView(t)
+	strategy := WeightedSpawn{Weights: map[string]float64{"Paris": 3, "Rome": 0, "Boston": 1}}
+	_, err := strategy.Spawn(1, view, rand.New(rand.NewSource(1)))
+	require.EqualError(t, err, "cannot spawn aliens in non existent city Rome")
+
+	strategy.Weights = map[string]float64{"Paris": 3, "Madrid": 0, "Boston": 1}
+	cities, err := strategy.Spawn(8000, view, rand.New(rand.NewSource(1)))
+	require.Nil(t, err)
+	counts := countCities(cities)
+	require.Len(t, counts, 2)
+	require.InDelta(t, 6000, counts["Paris"], 300)
+	require.InDelta(t, 2000, counts["Boston"], 300)
+}
+
+func TestClusterSpawnStaysWithinHops(t *testing.T) {
+	view := testView(t)
+	cities, err := ClusterSpawn{Center: "Porto", Hops: 0}.Spawn(10, view, rand.New(rand.NewSource(1)))
+	require.Nil(t, err)
+	require.Equal(t, map[string]int{"Porto": 10}, countCities(cities))
+
+	cities, err = ClusterSpawn{Center: "Porto", Hops: 2}.Spawn(1000, view, rand.New(rand.NewSource(1)))
+	require.Nil(t, err)
+	counts := countCities(cities)
+	// Porto -> Berlin, Madrid -> Belgrade, Prague, Boston, London
+	require.ElementsMatch(t, []string{"Porto", "Berlin", "Madrid", "Belgrade", "Prague", "Boston", "London"}, keys(counts))
+
+	_, err = ClusterSpawn{Center: "Rome", Hops: 1}.Spawn(1, view, rand.New(rand.NewSource(1)))
+	require.EqualError(t, err, "cannot spawn aliens around non existent city Rome")
+}
+
+func TestListSpawnCyclesThroughCities(t *testing.T) {
+	view := testView(t)
+	cities, err := ListSpawn{Cities: []string{"Paris", "Porto"}}.Spawn(5, view, nil)
+	require.Nil(t, err)
+	require.Equal(t, []string{"Paris", "Porto", "Paris", "Porto", "Paris"}, cities)
+
+	_, err = ListSpawn{Cities: []string{"Paris", "Rome"}}.Spawn(5, view, nil)
+	require.EqualError(t, err, "cannot spawn aliens in non existent city Rome")
+}
+
+func TestSpawnSkipsFullCities(t *testing.T) {
+	input := "Paris capacity=1 population=300 north=Berlin\nBerlin capacity=2 population=100 south=Paris\n"
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	view := newMapView(s.initialMap, s.initialMap.sortedNames())
+	for _, strategy := range []SpawnStrategy{
+		UniformSpawn{},
+		WeightedSpawn{Weights: map[string]float64{"Paris": 3, "Berlin": 1}},
+		ClusterSpawn{Center: "Paris", Hops: 1},
+		PopulationSpawn{},
+	} {
+		cities, err := strategy.Spawn(3, view, rand.New(rand.NewSource(1)))
+		require.Nil(t, err)
+		require.Equal(t, map[string]int{"Paris": 1, "Berlin": 2}, countCities(cities))
+
+		_, err = strategy.Spawn(4, view, rand.New(rand.NewSource(1)))
+		require.EqualError(t, err, "cannot spawn aliens, all cities are full")
+	}
+}
+
+func TestParseSpawnStrategy(t *testing.T) {
+	cases := []struct {
+		spec     string
+		expected SpawnStrategy
+		err      string
+	}{
+		{spec: "uniform", expected: UniformSpawn{}},
+		{spec: "weighted:Paris=3, Rome=0.5", expected: WeightedSpawn{Weights: map[string]float64{"Paris": 3, "Rome": 0.5}}},
+		{spec: "cluster:Paris:2", expected: ClusterSpawn{Center: "Paris", Hops: 2}},
+		{spec: "list:Paris,Rome", expected: ListSpawn{Cities: []string{"Paris", "Rome"}}},
+		{spec: "uniform:Paris", err: "uniform spawn strategy takes no arguments, got Paris"},
+		{spec: "weighted:Paris", err: "expected city=weight in weighted spawn strategy, got Paris"},
+		{spec: "cluster:Paris", err: "expected cluster:city:hops, got cluster:Paris"},
+		{spec: "cluster:Paris:x", err: "invalid number of hops in cluster spawn strategy: strconv.Atoi: parsing \"x\": invalid syntax"},
+		{spec: "list:", err: "list spawn strategy requires at least one city"},
+		{spec: "random", err: "unknown spawn strategy random, expected one of uniform,weighted,cluster,list,population"},
+	}
+	for _, tc := range cases {
+		strategy, err := ParseSpawnStrategy(tc.spec)
+		if tc.err != "" {
+			require.EqualError(t, err, tc.err, tc.spec)
+			continue
+		}
+		require.Nil(t, err, tc.spec)
+		require.Equal(t, tc.expected, strategy, tc.spec)
+	}
+}
+
+func TestSimulationUsesSpawnStrategy(t *testing.T) {
+	s, err := createSimulation(strings.NewReader(testMap), "testing")
+	require.Nil(t, err)
+	s.SetSeed(1)
+	cfg := DefaultSimulationConfig(2)
+	cfg.Spawn = ListSpawn{Cities: []string{"Paris"}}
+	res, err := s.Run(cfg)
+	require.Nil(t, err)
+	require.True(t, res.ResultMap["Paris"].isDestroyed)
+	require.Equal(t, "Aliens: 👾0, 👾1 have met in the city of Paris. ⚔ Battle destroyed the city.", res.Logs()[1])
+}
+
+// keys returns keys of the counts map
+func keys(counts map[string]int) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestPopulationSpawnFollowsPopulation(t *testing.T) {
+	s, err := createSimulation(strings.NewReader("Paris population=300 north=Berlin\nBerlin population=100 south=Paris east=Oslo\nOslo west=Berlin\n"), "testing")
+	require.Nil(t, err)
+	view := newMapView(s.initialMap, s.initialMap.sortedNames())
+	cities, err := PopulationSpawn{}.Spawn(8000, view, rand.New(rand.NewSource(1)))
+	require.Nil(t, err)
+	counts := countCities(cities)
+	require.Len(t, counts, 2)
+	require.InDelta(t, 6000, counts["Paris"], 300)
+	require.InDelta(t, 2000, counts["Berlin"], 300)
+
+	_, err = PopulationSpawn{}.Spawn(1, testView(t), rand.New(rand.NewSource(1)))
+	require.EqualError(t, err, "at least one city must have a positive population")
+}
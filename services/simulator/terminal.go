@@ -0,0 +1,203 @@
+package simulator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ivanovpetr/invasion/services/layout"
+)
+
+// Size of a city on the terminal grid in characters, the rest of a cell is taken by roads
+const (
+	terminalCellWidth  = 16
+	terminalCellHeight = 3
+	terminalCityWidth  = 12
+)
+
+// ANSI escape sequences of terminal styles
+const (
+	styleReset     = "\x1b[0m"
+	styleDestroyed = "\x1b[2;31m"
+	styleBattle    = "\x1b[1;97;41m"
+	styleAliens    = "\x1b[1;34m"
+	styleRoad      = "\x1b[90m"
+)
+
+// terminalCell is a character of the terminal grid with its style
+type terminalCell struct {
+	r     rune
+	style string
+}
+
+// TerminalGrid draws a map on a grid of characters derived from directions of roads,
+// so the north neighbour of a city is drawn above it
+type TerminalGrid struct {
+	cities    planetMap
+	positions map[string]layout.Point
+	columns   int
+	rows      int
+}
+
+// NewTerminalGrid lays out the map of the simulation on a grid of characters
+func NewTerminalGrid(s *Simulation) *TerminalGrid {
+	g := &TerminalGrid{cities: s.initialMap, positions: mapLayout(s.initialMap, s.topology).Positions}
+	for _, p := range g.positions {
+		if p.X+1 > g.columns {
+			g.columns = p.X + 1
+		}
+		if p.Y+1 > g.rows {
+			g.rows = p.Y + 1
+		}
+	}
+	return g
+}
+
+// Render draws the state of a run line by line. Every city shows its name and aliens in it, `*` is an alien
+// in the city and `~` is an alien on the road to it. Cities in flash are highlighted, with color styles
+// are ANSI escape sequences, without color destroyed cities are marked with `x` and highlighted ones with `!`
+func (g *TerminalGrid) Render(state RunState, flash map[string]bool, color bool) []string {
+	canvas := make([][]terminalCell, g.rows*terminalCellHeight)
+	for i := range canvas {
+		canvas[i] = make([]terminalCell, g.columns*terminalCellWidth)
+		for j := range canvas[i] {
+			canvas[i][j].r = ' '
+		}
+	}
+	write := func(row, column int, text, style string) {
+		for _, r := range text {
+			if column >= 0 && column < len(canvas[row]) {
+				canvas[row][column] = terminalCell{r: r, style: style}
+			}
+			column++
+		}
+	}
+
+	for _, name := range g.cities.sortedNames() {
+		for _, d := range g.cities[name].directions {
+			g.drawRoad(write, name, d.directionValue)
+		}
+	}
+
+	inCity, onRoad := map[string]int{}, map[string]int{}
+	for _, a := range state.Aliens {
+		switch {
+		case a.Dead:
+		case a.Transit > 0:
+			onRoad[a.City]++
+		default:
+			inCity[a.City]++
+		}
+	}
+	for _, c := range state.Cities {
+		p := g.positions[c.Name]
+		row, column := p.Y*terminalCellHeight, p.X*terminalCellWidth
+		name, aliens := fitText(c.Name, terminalCityWidth), fitText(alienMarks(inCity[c.Name], onRoad[c.Name]), terminalCityWidth)
+		nameStyle := ""
+		switch {
+		case flash[c.Name]:
+			nameStyle = styleBattle
+			if !color {
+				name = fitText("!"+c.Name, terminalCityWidth)
+			}
+		case c.Destroyed:
+			nameStyle = styleDestroyed
+			if !color {
+				name = fitText("x"+c.Name, terminalCityWidth)
+			}
+		}
+		write(row, column, name, nameStyle)
+		write(row+1, column, aliens, styleAliens)
+	}
+
+	lines := make([]string, 0, len(canvas))
+	for _, cells := range canvas {
+		builder := strings.Builder{}
+		style := ""
+		for _, cell := range cells {
+			if color && cell.style != style {
+				builder.WriteString(styleReset + cell.style)
+				style = cell.style
+			}
+			builder.WriteRune(cell.r)
+		}
+		if color && style != "" {
+			builder.WriteString(styleReset)
+		}
+		lines = append(lines, strings.TrimRight(builder.String(), " "))
+	}
+	return lines
+}
+
+// drawRoad draws the road between neighbour cities, roads between cities which aren't neighbours on the grid
+// are not drawn except straight roads over empty cells
+func (g *TerminalGrid) drawRoad(write func(row, column int, text, style string), from, to string) {
+	a, okA := g.positions[from]
+	b, okB := g.positions[to]
+	if !okA || !okB || a == b {
+		return
+	}
+	if b.X < a.X || b.X == a.X && b.Y < a.Y {
+		a, b = b, a
+	}
+	dx, dy := b.X-a.X, b.Y-a.Y
+	switch {
+	case dy == 0:
+		if !g.emptyCells(a, b, layout.Point{X: 1}) {
+			return
+		}
+		row := a.Y * terminalCellHeight
+		start := a.X*terminalCellWidth + terminalCityWidth
+		write(row, start, strings.Repeat("─", b.X*terminalCellWidth-start), styleRoad)
+	case dx == 0:
+		if !g.emptyCells(a, b, layout.Point{Y: 1}) {
+			return
+		}
+		column := a.X*terminalCellWidth + terminalCityWidth/2
+		for row := a.Y*terminalCellHeight + 2; row < b.Y*terminalCellHeight; row++ {
+			write(row, column, "│", styleRoad)
+		}
+	case dx == 1 && dy == 1:
+		write(a.Y*terminalCellHeight+2, a.X*terminalCellWidth+terminalCityWidth+1, "╲", styleRoad)
+	case dx == 1 && dy == -1:
+		write(b.Y*terminalCellHeight+2, b.X*terminalCellWidth-2, "╱", styleRoad)
+	}
+}
+
+// emptyCells checks that no city takes cells between the cities
+func (g *TerminalGrid) emptyCells(a, b, step layout.Point) bool {
+	for p := a.Add(step); p != b; p = p.Add(step) {
+		for _, q := range g.positions {
+			if q == p {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// alienMarks returns marks of aliens in the city and aliens on the road to it
+func alienMarks(inCity, onRoad int) string {
+	marks := ""
+	switch {
+	case inCity > 5:
+		marks = fmt.Sprintf("*x%d", inCity)
+	case inCity > 0:
+		marks = strings.Repeat("*", inCity)
+	}
+	if onRoad > 0 {
+		if marks != "" {
+			marks += " "
+		}
+		marks += fmt.Sprintf("~%d", onRoad)
+	}
+	return marks
+}
+
+// fitText cuts the text to the width or pads it with spaces
+func fitText(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-len(runes))
+}
@@ -0,0 +1,68 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerminalGridRendersState(t *testing.T) {
+	input := `Paris east=Rome south=Madrid
+Rome west=Paris
+Madrid north=Paris east=Lisbon:3
+Lisbon west=Madrid:3`
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	grid := NewTerminalGrid(s)
+	state := RunState{
+		Cities: []CityState{
+			{Name: "Lisbon"},
+			{Name: "Madrid"},
+			{Name: "Paris", Destroyed: true},
+			{Name: "Rome"},
+		},
+		Aliens: []AlienInfo{
+			{ID: 0, City: "Rome"},
+			{ID: 1, City: "Rome"},
+			{ID: 2, City: "Lisbon", Transit: 2},
+			{ID: 3, City: "Paris", Dead: true},
+		},
+	}
+	require.Equal(t, []string{
+		"xParis      ────Rome",
+		"                **",
+		"      │",
+		"!Madrid     ────Lisbon",
+		"                ~1",
+		"",
+	}, grid.Render(state, map[string]bool{"Madrid": true}, false))
+
+	colored := grid.Render(state, nil, true)
+	require.Equal(t, styleReset+styleDestroyed+"Paris       "+styleReset+styleRoad+"────"+styleReset+"Rome", colored[0])
+}
+
+func TestTerminalGridDrawsDiagonalRoads(t *testing.T) {
+	input := `@topology octagonal
+A southeast=B
+B northwest=A northeast=C
+C southwest=B`
+	s, err := createSimulation(strings.NewReader(input), "testing")
+	require.Nil(t, err)
+	lines := NewTerminalGrid(s).Render(RunState{Cities: []CityState{{Name: "A"}, {Name: "B"}, {Name: "C"}}}, nil, false)
+	require.Equal(t, []string{
+		"A                               C",
+		"",
+		"             ╲                ╱",
+		"                B",
+		"",
+		"",
+	}, lines[:6])
+}
+
+func TestAlienMarks(t *testing.T) {
+	require.Equal(t, "", alienMarks(0, 0))
+	require.Equal(t, "***", alienMarks(3, 0))
+	require.Equal(t, "*x7 ~2", alienMarks(7, 2))
+	require.Equal(t, "~1", alienMarks(0, 1))
+}
@@ -0,0 +1,195 @@
+package simulator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Names of built-in topologies
+const (
+	TopologyCompass   = "compass"
+	TopologyOctagonal = "octagonal"
+	TopologyHex       = "hex"
+
+	topologyCustom = "custom"
+)
+
+// topologyDirective declares the topology of a map in its header, for example `@topology hex`
+const topologyDirective = "@topology"
+
+// Topology describes the set of directions roads of a map may have and which directions are opposite to each other
+type Topology struct {
+	name string
+	// directions in the order cities get them from the parser
+	directions []string
+	// order of directions in the canonical map format
+	order []string
+	// opposite direction of every direction which has one
+	opposites map[string]string
+}
+
+// newTopology creates a topology from pairs of opposite directions. A pair with an empty second direction
+// declares a direction without an opposite one
+func newTopology(name string, pairs [][2]string) (*Topology, error) {
+	t := &Topology{name: name, opposites: map[string]string{}}
+	seen := map[string]bool{}
+	for _, pair := range pairs {
+		for _, d := range pair {
+			if d == "" {
+				continue
+			}
+			if !isValidDirection(d) {
+				return nil, fmt.Errorf("expected a valid direction name, got %s", d)
+			}
+			if seen[d] {
+				return nil, fmt.Errorf("got direction duplication %s", d)
+			}
+			seen[d] = true
+			t.directions = append(t.directions, d)
+		}
+		if pair[1] != "" {
+			t.opposites[pair[0]] = pair[1]
+			t.opposites[pair[1]] = pair[0]
+		}
+	}
+	if len(t.directions) == 0 {
+		return nil, fmt.Errorf("topology must contain at least one direction")
+	}
+	t.order = t.directions
+	return t, nil
+}
+
+// mustTopology creates a built-in topology
+func mustTopology(name string, pairs [][2]string, order []string) *Topology {
+	t, err := newTopology(name, pairs)
+	if err != nil {
+		panic(err)
+	}
+	if order != nil {
+		t.order = order
+	}
+	return t
+}
+
+var (
+	// compassTopology keeps the historical order of directions, so seeded simulations of old maps don't change
+	compassTopology = mustTopology(TopologyCompass, [][2]string{
+		{directionSouth, directionNorth},
+		{directionWest, directionEast},
+	}, []string{directionNorth, directionEast, directionSouth, directionWest})
+
+	octagonalTopology = mustTopology(TopologyOctagonal, [][2]string{
+		{directionNorth, directionSouth},
+		{"northeast", "southwest"},
+		{directionEast, directionWest},
+		{"southeast", "northwest"},
+	}, []string{directionNorth, "northeast", directionEast, "southeast", directionSouth, "southwest", directionWest, "northwest"})
+
+	// hexTopology describes a grid of pointy-topped hexagons
+	hexTopology = mustTopology(TopologyHex, [][2]string{
+		{"northeast", "southwest"},
+		{directionEast, directionWest},
+		{"southeast", "northwest"},
+	}, []string{"northeast", directionEast, "southeast", "southwest", directionWest, "northwest"})
+)
+
+// DefaultTopology returns the topology of maps without a topology declaration: north, east, south and west
+func DefaultTopology() *Topology {
+	return compassTopology
+}
+
+// canonicalDirections returns directions of a city in the canonical order of the topology
+func (t *Topology) canonicalDirections(directions []mapDirection) []mapDirection {
+	sorted := make([]mapDirection, 0, len(directions))
+	for _, o := range t.order {
+		for _, d := range directions {
+			if d.directionType == o {
+				sorted = append(sorted, d)
+			}
+		}
+	}
+	return sorted
+}
+
+// ParseTopology parses a topology specification. Supported specifications are names of built-in topologies
+// compass, octagonal and hex, or a custom set of directions separated by spaces, where opposite directions
+// are joined with a colon, for example `up:down left:right portal`
+func ParseTopology(spec string) (*Topology, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("topology must not be empty")
+	}
+	if len(fields) == 1 && !strings.Contains(fields[0], ":") {
+		switch fields[0] {
+		case TopologyCompass:
+			return compassTopology, nil
+		case TopologyOctagonal:
+			return octagonalTopology, nil
+		case TopologyHex:
+			return hexTopology, nil
+		default:
+			return nil, fmt.Errorf("unknown topology %s, expected one of compass,octagonal,hex or a list of directions", fields[0])
+		}
+	}
+	pairs := make([][2]string, 0, len(fields))
+	for _, field := range fields {
+		parts := strings.Split(field, ":")
+		switch {
+		case len(parts) == 1:
+			pairs = append(pairs, [2]string{parts[0], ""})
+		case len(parts) == 2 && parts[1] != "":
+			pairs = append(pairs, [2]string{parts[0], parts[1]})
+		default:
+			return nil, fmt.Errorf("invalid pair of directions %s, expected direction:opposite", field)
+		}
+	}
+	return newTopology(topologyCustom, pairs)
+}
+
+// Name returns the name of the topology, custom for topologies declared as a list of directions
+func (t *Topology) Name() string {
+	return t.name
+}
+
+// Directions returns all directions of the topology
+func (t *Topology) Directions() []string {
+	directions := make([]string, len(t.directions))
+	copy(directions, t.directions)
+	return directions
+}
+
+// Has checks whether the topology has the direction or not
+func (t *Topology) Has(direction string) bool {
+	for _, d := range t.directions {
+		if d == direction {
+			return true
+		}
+	}
+	return false
+}
+
+// Opposite returns the direction which leads back or an empty string if the direction has no opposite one
+func (t *Topology) Opposite(direction string) string {
+	return t.opposites[direction]
+}
+
+// String returns the specification of the topology which ParseTopology accepts
+func (t *Topology) String() string {
+	if t.name != topologyCustom {
+		return t.name
+	}
+	fields := make([]string, 0, len(t.directions))
+	written := map[string]bool{}
+	for _, d := range t.directions {
+		if written[d] {
+			continue
+		}
+		written[d] = true
+		if opposite := t.opposites[d]; opposite != "" {
+			written[opposite] = true
+			d += ":" + opposite
+		}
+		fields = append(fields, d)
+	}
+	return strings.Join(fields, " ")
+}
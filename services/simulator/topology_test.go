@@ -0,0 +1,56 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBuiltInTopologies(t *testing.T) {
+	for _, name := range []string{TopologyCompass, TopologyOctagonal, TopologyHex} {
+		topology, err := ParseTopology(name)
+		require.Nil(t, err)
+		require.Equal(t, name, topology.Name())
+		require.Equal(t, name, topology.String())
+		for _, d := range topology.Directions() {
+			require.True(t, topology.Has(topology.Opposite(d)), d)
+			require.Equal(t, d, topology.Opposite(topology.Opposite(d)))
+		}
+	}
+	octagonal, _ := ParseTopology(TopologyOctagonal)
+	require.Len(t, octagonal.Directions(), 8)
+	require.Equal(t, "southwest", octagonal.Opposite("northeast"))
+	hex, _ := ParseTopology(TopologyHex)
+	require.Len(t, hex.Directions(), 6)
+	require.False(t, hex.Has(directionNorth))
+}
+
+func TestParseCustomTopology(t *testing.T) {
+	topology, err := ParseTopology("  up:down left:right portal ")
+	require.Nil(t, err)
+	require.Equal(t, "custom", topology.Name())
+	require.Equal(t, []string{"up", "down", "left", "right", "portal"}, topology.Directions())
+	require.Equal(t, "down", topology.Opposite("up"))
+	require.Equal(t, "left", topology.Opposite("right"))
+	require.Equal(t, "", topology.Opposite("portal"))
+	require.Equal(t, "up:down left:right portal", topology.String())
+}
+
+func TestParseTopologyFailsOnInvalidSpec(t *testing.T) {
+	testCases := []struct {
+		spec string
+		err  string
+	}{
+		{spec: "", err: "topology must not be empty"},
+		{spec: "square", err: "unknown topology square, expected one of compass,octagonal,hex or a list of directions"},
+		{spec: "up:down up:left", err: "got direction duplication up"},
+		{spec: "up:up", err: "got direction duplication up"},
+		{spec: "up:", err: "invalid pair of directions up:, expected direction:opposite"},
+		{spec: "a:b:c", err: "invalid pair of directions a:b:c, expected direction:opposite"},
+		{spec: "up:1down", err: "expected a valid direction name, got 1down"},
+	}
+	for _, tc := range testCases {
+		_, err := ParseTopology(tc.spec)
+		require.EqualError(t, err, tc.err, tc.spec)
+	}
+}
@@ -1,28 +1,72 @@
 package simulator
 
-import "regexp"
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
 
-const cityConstraint = "^[a-zA-Z1-9\\-\\_]+$"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	cityConstraint       = "^[\\p{L}\\p{M}\\p{N}\\-\\_]+$"
+	quotedCityConstraint = "^[\\p{L}\\p{M}\\p{N}\\-\\_]+( [\\p{L}\\p{M}\\p{N}\\-\\_]+)*$"
+	directionConstraint  = "^[a-zA-Z][a-zA-Z0-9\\-\\_]*$"
+)
 
 var (
-	cityRegex = regexp.MustCompile(cityConstraint)
+	cityRegex       = regexp.MustCompile(cityConstraint)
+	quotedCityRegex = regexp.MustCompile(quotedCityConstraint)
+	directionRegex  = regexp.MustCompile(directionConstraint)
 )
 
+// isValidCityName checks whether the name may be written in a map without quotes
 func isValidCityName(name string) bool {
 	return cityRegex.Match([]byte(name))
 }
 
-func isValidDirection(name string) bool {
-	switch name {
-	case directionNorth:
-		return true
-	case directionSouth:
-		return true
-	case directionEast:
-		return true
-	case directionWest:
-		return true
-	default:
-		return false
+// isValidQuotedCityName checks whether the name may be written in a map in quotes. Quoted names may contain
+// single spaces between words
+func isValidQuotedCityName(name string) bool {
+	return quotedCityRegex.Match([]byte(name))
+}
+
+// cityName returns the city name the token holds. Quoted names are unquoted and all names are normalized.
+// False is returned for tokens which are not valid city names
+func cityName(token string) (string, bool) {
+	if strings.HasPrefix(token, `"`) {
+		name, err := strconv.Unquote(token)
+		if err != nil {
+			return token, false
+		}
+		name = normalizeCityName(name)
+		return name, isValidQuotedCityName(name)
+	}
+	name := normalizeCityName(token)
+	return name, isValidCityName(name)
+}
+
+// isCityNameRune checks whether the character may be a part of a city name written without quotes
+func isCityNameRune(ch rune) bool {
+	return ch == '-' || ch == '_' || unicode.In(ch, unicode.L, unicode.M, unicode.N)
+}
+
+// normalizeCityName returns the name in the Unicode normalization form C, so the same name typed
+// with combining characters or with precomposed ones is the same city
+func normalizeCityName(name string) string {
+	return norm.NFC.String(name)
+}
+
+// quoteCityName returns the name the way it must be written in a map, names with spaces are quoted
+func quoteCityName(name string) string {
+	if isValidCityName(name) {
+		return name
 	}
+	return strconv.Quote(name)
+}
+
+// isValidDirection checks whether the name may be used as a direction of a topology
+func isValidDirection(name string) bool {
+	return directionRegex.Match([]byte(name))
 }
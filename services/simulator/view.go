@@ -0,0 +1,85 @@
+package simulator
+
+// Road is a read-only representation of a direction which leads from one city to another
+type Road struct {
+	Direction string `json:"direction"`
+	City      string `json:"city"`
+	// Transit is the number of turns an alien spends on the road after the first one, 0 for ordinary roads
+	Transit int `json:"transit,omitempty"`
+}
+
+// Turns returns the number of turns an alien needs to travel along the road
+func (r Road) Turns() int {
+	return r.Transit + 1
+}
+
+// MapView is a read-only view of a simulation map which is given to strategies
+type MapView interface {
+	// Cities returns names of all cities on the map in alphabetical order
+	Cities() []string
+	// HasCity checks whether the city exists on the map or not
+	HasCity(name string) bool
+	// Roads returns all roads which lead out of the city
+	Roads(name string) []Road
+	// IsDestroyed checks whether the city is destroyed or not
+	IsDestroyed(name string) bool
+	// Aliens returns number of aliens located in the city
+	Aliens(name string) int
+	// Attributes returns attributes of the city, for example its population
+	Attributes(name string) Attributes
+}
+
+// mapView implements MapView on top of a simulation map
+type mapView struct {
+	cities planetMap
+	names  []string
+}
+
+// newMapView creates a read-only view of the provided map
+func newMapView(p planetMap, names []string) *mapView {
+	return &mapView{cities: p, names: names}
+}
+
+func (v *mapView) Cities() []string {
+	names := make([]string, len(v.names))
+	copy(names, v.names)
+	return names
+}
+
+func (v *mapView) HasCity(name string) bool {
+	_, ok := v.cities[name]
+	return ok
+}
+
+func (v *mapView) Roads(name string) []Road {
+	c, ok := v.cities[name]
+	if !ok {
+		return nil
+	}
+	roads := make([]Road, 0, len(c.directions))
+	for _, d := range c.directions {
+		roads = append(roads, d.road())
+	}
+	return roads
+}
+
+func (v *mapView) IsDestroyed(name string) bool {
+	c, ok := v.cities[name]
+	return ok && c.isDestroyed
+}
+
+func (v *mapView) Aliens(name string) int {
+	c, ok := v.cities[name]
+	if !ok {
+		return 0
+	}
+	return len(c.aliens)
+}
+
+func (v *mapView) Attributes(name string) Attributes {
+	c, ok := v.cities[name]
+	if !ok {
+		return nil
+	}
+	return c.attributes.copy()
+}